@@ -1,7 +1,7 @@
 package adapters
 
 import (
-	"fmt"
+	"github.com/sealdice/smallseal/dice/types"
 )
 
 // PlatformAdapter 平台适配器接口 - 纯协议接口，不依赖业务上下文
@@ -34,6 +34,10 @@ type PlatformAdapter interface {
 	FriendDelete(request *FriendOperationRequest) (bool, error)
 	FriendAdd(request *FriendOperationRequest) (bool, error)
 
+	// 申请处理
+	FriendRequestSet(request *FriendRequestSetRequest) (bool, error)
+	GroupInviteSet(request *GroupInviteSetRequest) (bool, error)
+
 	// 群文件操作 - 扩展区，不用实现
 	GroupFileList(request *GroupFileListRequest) (*GroupFileListResponse, error)
 	GroupFileDownload(request *GroupFileDownloadRequest) (*GroupFileDownloadResponse, error)
@@ -44,22 +48,52 @@ type PlatformAdapter interface {
 
 // 实现检查
 var (
-// _ PlatformAdapter = (*PlatformAdapterMilky)(nil)
-// _ PlatformAdapter = (*PlatformAdapterLagrangeGo)(nil)
+	_ PlatformAdapter = (*PlatformAdapterMilky)(nil)
+	_ PlatformAdapter = (*PlatformAdapterOB11)(nil)
+
+	_ PlatformIdentifier = (*PlatformAdapterMilky)(nil)
+	_ PlatformIdentifier = (*PlatformAdapterOB11)(nil)
+	// _ PlatformAdapter = (*PlatformAdapterLagrangeGo)(nil)
 )
 
+// PlatformIdentifier 适配器声明自身的平台标识，如 QQ、TG、DISCORD，用作用户ID与群组ID的前缀
+// 注册到 Dice 的适配器实现此接口时，指令解析与@识别按该平台处理
+type PlatformIdentifier interface {
+	Platform() string
+}
+
+// FormatDiceID 拼接带平台前缀的用户ID，如 TG:123
+func FormatDiceID(platform, userID string) string {
+	return types.FormatDiceID(platform, userID)
+}
+
+// FormatDiceIDGroup 拼接带平台前缀的群组ID，如 TG-Group:123
+func FormatDiceIDGroup(platform, groupID string) string {
+	return types.FormatDiceIDGroup(platform, groupID)
+}
+
+// FormatDiceIDCh 拼接带平台前缀的频道用户ID，如 QQ-CH:123
+func FormatDiceIDCh(platform, userID string) string {
+	return types.FormatDiceIDCh(platform, userID)
+}
+
+// FormatDiceIDChGroup 拼接子频道对应的群组ID，如 QQ-CH-Group:服务器-子频道
+func FormatDiceIDChGroup(platform, guildID, channelID string) string {
+	return types.ChannelGroupID(platform, guildID, channelID)
+}
+
 func FormatDiceIDQQ(diceQQ string) string {
-	return fmt.Sprintf("QQ:%s", diceQQ)
+	return FormatDiceID("QQ", diceQQ)
 }
 
 func FormatDiceIDQQGroup(diceQQ string) string {
-	return fmt.Sprintf("QQ-Group:%s", diceQQ)
+	return FormatDiceIDGroup("QQ", diceQQ)
 }
 
 func FormatDiceIDQQCh(userID string) string {
-	return fmt.Sprintf("QQ-CH:%s", userID)
+	return FormatDiceIDCh("QQ", userID)
 }
 
 func FormatDiceIDQQChGroup(guildID, channelID string) string {
-	return fmt.Sprintf("QQ-CH-Group:%s-%s", guildID, channelID)
+	return FormatDiceIDChGroup("QQ", guildID, channelID)
 }
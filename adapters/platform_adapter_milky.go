@@ -1,6 +1,7 @@
 package adapters
 
 import (
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"strconv"
@@ -41,6 +42,9 @@ type PlatformAdapterMilky struct {
 
 	// 连接状态
 	isAlive bool
+
+	// 登录账号，如 QQ:123，用于区分同一群内的多个骰子账号
+	selfID string
 }
 
 // SetCallback 设置回调接口
@@ -53,6 +57,11 @@ func (pa *PlatformAdapterMilky) IsAlive() bool {
 	return pa.IntentSession != nil && pa.isAlive
 }
 
+// Platform 平台标识，Milky 协议对接的是QQ
+func (pa *PlatformAdapterMilky) Platform() string {
+	return "QQ"
+}
+
 // MsgSendToGroup 发送消息到群组
 func (pa *PlatformAdapterMilky) MsgSendToGroup(request *MessageSendRequest) (bool, error) {
 	log := zap.S().Named("adapter")
@@ -307,6 +316,7 @@ func (pa *PlatformAdapterMilky) Serve() int {
 			Platform: "QQ",
 			Time:     m.Time,
 			RawID:    m.MessageSeq,
+			SelfID:   pa.selfID,
 			Sender: types.SenderBase{
 				UserID: FormatDiceIDQQ(strconv.FormatInt(m.SenderId, 10)),
 			},
@@ -508,6 +518,12 @@ func (pa *PlatformAdapterMilky) Serve() int {
 		)
 	})
 
+	if info, err := session.GetLoginInfo(); err == nil {
+		pa.selfID = formatMilkyUserID(info.UIN)
+	} else {
+		log.Warnf("Failed to get Milky login info: %v", err)
+	}
+
 	err = session.Open()
 	if err != nil {
 		log.Errorf("Milky Connect Error:%s", err.Error())
@@ -996,11 +1012,103 @@ func (pa *PlatformAdapterMilky) MsgRecall(request *MessageOperationRequest) (boo
 	return false, err
 }
 
+// FriendRequestSet 处理好友申请
+func (pa *PlatformAdapterMilky) FriendRequestSet(request *FriendRequestSetRequest) (bool, error) {
+	log := zap.S().Named("adapter")
+
+	var err error
+	if request.Approve {
+		err = pa.IntentSession.AcceptFriendRequest(request.Flag)
+	} else {
+		err = pa.callAPI(milky.EndpointRejectFriendRequest, map[string]any{
+			"request_id": request.Flag,
+			"reason":     request.Reason,
+		})
+	}
+	if err != nil {
+		log.Errorf("Failed to handle friend request %s: %v", request.Flag, err)
+		if pa.callback != nil {
+			pa.callback.OnError(err)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// GroupInviteSet 处理入群邀请
+func (pa *PlatformAdapterMilky) GroupInviteSet(request *GroupInviteSetRequest) (bool, error) {
+	log := zap.S().Named("adapter")
+
+	var groupIDStr string
+	switch v := request.GroupID.(type) {
+	case string:
+		groupIDStr = ExtractQQGroupID(v)
+	case int64:
+		groupIDStr = strconv.FormatInt(v, 10)
+	default:
+		err := fmt.Errorf("invalid group ID type: %T", request.GroupID)
+		log.Error(err)
+		if pa.callback != nil {
+			pa.callback.OnError(err)
+		}
+		return false, err
+	}
+
+	groupID, err := strconv.ParseInt(groupIDStr, 10, 64)
+	if err != nil {
+		log.Errorf("Invalid group ID %s: %v", groupIDStr, err)
+		if pa.callback != nil {
+			pa.callback.OnError(err)
+		}
+		return false, err
+	}
+
+	if request.Approve {
+		err = pa.IntentSession.AcceptGroupInviteRequest(groupID, request.Flag)
+	} else {
+		err = pa.callAPI(milky.EndpointRejectGroupInviteRequest, map[string]any{
+			"group_id":   groupID,
+			"request_id": request.Flag,
+		})
+	}
+	if err != nil {
+		log.Errorf("Failed to handle group invitation %s: %v", request.Flag, err)
+		if pa.callback != nil {
+			pa.callback.OnError(err)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// callAPI 调用 SDK 未封装的接口
+func (pa *PlatformAdapterMilky) callAPI(endpoint string, params map[string]any) error {
+	data, err := pa.IntentSession.Request("POST", endpoint, params)
+	if err != nil {
+		return err
+	}
+	var resp milky.APIResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+	if resp.RetCode != 0 || resp.Status != "ok" {
+		return fmt.Errorf("API call failed: %s", resp.Message)
+	}
+	return nil
+}
+
 func (pa *PlatformAdapterMilky) emitAdapterEvent(postType, eventType, subType string, timestamp int64, groupID, userID, operatorID string, raw map[string]any) {
 	if pa.callback == nil {
 		return
 	}
 
+	if pa.selfID != "" {
+		if raw == nil {
+			raw = map[string]any{}
+		}
+		raw["self_id"] = pa.selfID
+	}
+
 	evt := &types.AdapterEvent{
 		Platform:   "QQ",
 		PostType:   postType,
@@ -1149,3 +1257,16 @@ func (pa *PlatformAdapterMilky) GroupFileDownload(request *GroupFileDownloadRequ
 	}
 	return nil, err
 }
+
+// GroupFileUpload 上传群文件 - 占位符实现
+func (pa *PlatformAdapterMilky) GroupFileUpload(request *GroupFileUploadRequest) (bool, error) {
+	log := zap.S().Named("adapter")
+
+	// TODO: 实现 Milky SDK 的群文件上传功能
+	err := fmt.Errorf("group file upload not implemented for Milky adapter")
+	log.Warn(err.Error())
+	if pa.callback != nil {
+		pa.callback.OnError(err)
+	}
+	return false, err
+}
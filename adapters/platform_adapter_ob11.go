@@ -85,6 +85,11 @@ func (pa *PlatformAdapterOB11) IsAlive() bool {
 	return pa.running.Load()
 }
 
+// Platform reports the platform identity; OneBot 11 implementations serve QQ.
+func (pa *PlatformAdapterOB11) Platform() string {
+	return "QQ"
+}
+
 // Serve boots reverse and/or forward websocket endpoints.
 func (pa *PlatformAdapterOB11) Serve(ctx context.Context) {
 	if ctx == nil {
@@ -555,6 +560,10 @@ func (pa *PlatformAdapterOB11) getAPISession() *ob11Session {
 
 // MsgSendToGroup sends a message to a group.
 func (pa *PlatformAdapterOB11) MsgSendToGroup(request *MessageSendRequest) (bool, error) {
+	if guildID, channelID, ok := types.ParseChannelGroupID(formatAnyID(request.TargetId)); ok {
+		return pa.msgSendToChannel(request, guildID, channelID)
+	}
+
 	gid, err := parseInt64(ExtractQQGroupID(formatAnyID(request.TargetId)))
 	if err != nil {
 		return false, err
@@ -574,6 +583,23 @@ func (pa *PlatformAdapterOB11) MsgSendToGroup(request *MessageSendRequest) (bool
 	return true, nil
 }
 
+// msgSendToChannel sends a message to a guild channel.
+func (pa *PlatformAdapterOB11) msgSendToChannel(request *MessageSendRequest, guildID, channelID string) (bool, error) {
+	params := map[string]any{
+		"guild_id":   guildID,
+		"channel_id": channelID,
+		"message":    pa.buildMessage(request.Segments),
+	}
+
+	var resp ob11SendResponse
+	if err := pa.callAction(context.Background(), "send_guild_channel_msg", params, &resp); err != nil {
+		return false, err
+	}
+
+	pa.emitEcho(request.Segments, request.Sender, "guild", FormatDiceIDQQChGroup(guildID, channelID), sanitizeRawMessage(resp.MessageID))
+	return true, nil
+}
+
 // MsgSendToPerson sends a private message.
 func (pa *PlatformAdapterOB11) MsgSendToPerson(request *MessageSendRequest) (bool, error) {
 	uid, err := parseInt64(ExtractQQUserID(formatAnyID(request.TargetId)))
@@ -760,6 +786,47 @@ func (pa *PlatformAdapterOB11) FriendAdd(_ *FriendOperationRequest) (bool, error
 	return false, errors.New("ob11 adapter: friend add not supported")
 }
 
+// FriendRequestSet approves or rejects a friend request.
+func (pa *PlatformAdapterOB11) FriendRequestSet(request *FriendRequestSetRequest) (bool, error) {
+	if request.Flag == "" {
+		return false, errors.New("ob11 adapter: request flag is empty")
+	}
+
+	params := map[string]any{
+		"flag":    request.Flag,
+		"approve": request.Approve,
+	}
+	if request.Approve && request.Remark != "" {
+		params["remark"] = request.Remark
+	}
+
+	if err := pa.callAction(context.Background(), "set_friend_add_request", params, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GroupInviteSet approves or rejects an invitation to join a group.
+func (pa *PlatformAdapterOB11) GroupInviteSet(request *GroupInviteSetRequest) (bool, error) {
+	if request.Flag == "" {
+		return false, errors.New("ob11 adapter: request flag is empty")
+	}
+
+	params := map[string]any{
+		"flag":     request.Flag,
+		"sub_type": "invite",
+		"approve":  request.Approve,
+	}
+	if !request.Approve && request.Reason != "" {
+		params["reason"] = request.Reason
+	}
+
+	if err := pa.callAction(context.Background(), "set_group_add_request", params, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func (pa *PlatformAdapterOB11) convertEventToMessage(evt *ob11EventEnvelope) *types.Message {
 	segments := pa.extractSegments(evt.Message)
 	if len(segments) == 0 && evt.RawMessage != "" {
@@ -786,6 +853,10 @@ func (pa *PlatformAdapterOB11) convertEventToMessage(evt *ob11EventEnvelope) *ty
 		},
 	}
 
+	if selfID := sanitizeRawMessage(evt.SelfID); selfID != "" {
+		msg.SelfID = FormatDiceIDQQ(selfID)
+	}
+
 	if evt.MessageType == "group" {
 		msg.GroupID = FormatDiceIDQQGroup(evt.groupID())
 	}
@@ -793,6 +864,7 @@ func (pa *PlatformAdapterOB11) convertEventToMessage(evt *ob11EventEnvelope) *ty
 	if evt.MessageType == "guild" {
 		msg.GuildID = evt.guildID()
 		msg.ChannelID = evt.channelID()
+		msg.GroupID = FormatDiceIDQQChGroup(msg.GuildID, msg.ChannelID)
 	}
 
 	if len(msg.Segments) == 0 {
@@ -953,6 +1025,9 @@ func (pa *PlatformAdapterOB11) emitEcho(segs []types.IMessageElement, sender *Si
 	switch messageType {
 	case "group":
 		msg.GroupID = targetID
+	case "guild":
+		msg.GroupID = targetID
+		msg.GuildID, msg.ChannelID, _ = types.ParseChannelGroupID(targetID)
 	case "private":
 		msg.Sender.UserID = targetID
 		if msg.Sender.Nickname == "" {
@@ -1037,6 +1112,7 @@ type ob11Segment struct {
 
 type ob11EventEnvelope struct {
 	PostType    string          `json:"post_type"`
+	SelfID      json.RawMessage `json:"self_id"`
 	MessageType string          `json:"message_type"`
 	Time        int64           `json:"time"`
 	RawMessage  string          `json:"raw_message"`
@@ -86,6 +86,24 @@ type FriendOperationRequest struct {
 	UserID any // 用户ID
 }
 
+// FriendRequestSetRequest 处理好友申请请求
+type FriendRequestSetRequest struct {
+	RequestBase
+	Flag    string // 申请标识，OneBot 为 flag，Milky 为 request_id
+	Approve bool   // 是否同意
+	Remark  string // 同意后的好友备注，可不填
+	Reason  string // 拒绝理由，可不填
+}
+
+// GroupInviteSetRequest 处理入群邀请请求
+type GroupInviteSetRequest struct {
+	RequestBase
+	GroupID any    // 群组ID
+	Flag    string // 申请标识，OneBot 为 flag，Milky 为 request_id
+	Approve bool   // 是否同意
+	Reason  string // 拒绝理由，可不填
+}
+
 // GroupFileListRequest 获取群文件列表请求
 type GroupFileListRequest struct {
 	RequestBase
@@ -0,0 +1,299 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sealdice/smallseal/adapters"
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+// ReplyRoutePolicy 回复投递策略，决定 MsgToReply.AdapterId 对应的适配器不存在时如何处理
+type ReplyRoutePolicy int
+
+const (
+	// ReplyRouteStrict 仅投递到来源适配器，找不到时返回错误
+	ReplyRouteStrict ReplyRoutePolicy = iota
+	// ReplyRouteFallback 优先投递到来源适配器，找不到时投递到 Config.DefaultAdapterId
+	ReplyRouteFallback
+	// ReplyRouteBroadcast 投递到所有已注册的适配器(旧行为)
+	ReplyRouteBroadcast
+)
+
+var (
+	ErrAdapterNotFound     = errors.New("adapter not found")
+	ErrSendFileUnsupported = errors.New("adapter does not support sending files")
+)
+
+type adapterEntry struct {
+	id       string
+	adapter  adapters.PlatformAdapter // 仅注册了发送回调时为空
+	send     func(msg *types.MsgToReply) error
+	platform string // 平台标识，为空时使用 Config.PlatformPrefix
+}
+
+// RegisterAdapter 以 adapterID 注册平台适配器，回复会经由适配器的 MsgSendToGroup/MsgSendToPerson 发出
+// adapterID 需要与调用 Execute 时传入的一致，适配器实现 adapters.PlatformIdentifier 时按其声明的平台处理
+func (d *Dice) RegisterAdapter(adapterID string, adapter adapters.PlatformAdapter) error {
+	if adapter == nil {
+		return errors.New("adapter must not be nil")
+	}
+	entry := &adapterEntry{
+		id:      adapterID,
+		adapter: adapter,
+		send: func(msg *types.MsgToReply) error {
+			return sendReplyByAdapter(adapter, msg)
+		},
+	}
+	if identifier, ok := adapter.(adapters.PlatformIdentifier); ok {
+		entry.platform = identifier.Platform()
+	}
+	return d.registerAdapterEntry(entry)
+}
+
+// RegisterAdapterSender 以回调形式注册一个仅负责发送的适配器，适用于控制台、测试等场景
+func (d *Dice) RegisterAdapterSender(adapterID string, send func(msg *types.MsgToReply)) error {
+	if send == nil {
+		return errors.New("adapter sender must not be nil")
+	}
+	if err := d.registerAdapterEntry(&adapterEntry{
+		id: adapterID,
+		send: func(msg *types.MsgToReply) error {
+			send(msg)
+			return nil
+		},
+	}); err != nil {
+		return err
+	}
+	d.CallbackForSendMsg.Store(adapterID, send)
+	return nil
+}
+
+func (d *Dice) registerAdapterEntry(entry *adapterEntry) error {
+	if entry.id == "" {
+		return errors.New("adapter id must not be empty")
+	}
+	if _, loaded := d.adapterMap.LoadOrStore(entry.id, entry); loaded {
+		return fmt.Errorf("adapter %q already registered", entry.id)
+	}
+	return nil
+}
+
+// SetAdapterPlatform 声明已注册适配器的平台标识，如 TG、DISCORD
+// 来自该适配器的消息按此平台解析@与补全用户ID，未声明时使用 Config.PlatformPrefix
+func (d *Dice) SetAdapterPlatform(adapterID string, platform string) error {
+	if strings.Contains(platform, ":") {
+		return fmt.Errorf("platform %q must not contain ':'", platform)
+	}
+	entry, ok := d.adapterMap.Load(adapterID)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrAdapterNotFound, adapterID)
+	}
+	// 替换为新的条目，避免与正在处理的消息竞争
+	updated := *entry
+	updated.platform = platform
+	d.adapterMap.Store(adapterID, &updated)
+	return nil
+}
+
+// adapterPlatform 返回适配器的平台标识，未声明时使用配置中的平台前缀
+func (d *Dice) adapterPlatform(adapterID string, cfg Config) string {
+	if entry, ok := d.adapterMap.Load(adapterID); ok && entry.platform != "" {
+		return entry.platform
+	}
+	return cfg.PlatformPrefix
+}
+
+// UnregisterAdapter 移除已注册的适配器
+func (d *Dice) UnregisterAdapter(adapterID string) bool {
+	d.CallbackForSendMsg.Delete(adapterID)
+	return d.adapterMap.Delete(adapterID)
+}
+
+// GetAdapter 获取指定ID的平台适配器，仅注册了发送回调的适配器会返回 false
+func (d *Dice) GetAdapter(adapterID string) (adapters.PlatformAdapter, bool) {
+	entry, ok := d.adapterMap.Load(adapterID)
+	if !ok || entry.adapter == nil {
+		return nil, false
+	}
+	return entry.adapter, true
+}
+
+// ListAdapters 列出所有已注册的适配器ID
+func (d *Dice) ListAdapters() []string {
+	ids := make([]string, 0)
+	d.adapterMap.Range(func(key string, _ *adapterEntry) bool {
+		ids = append(ids, key)
+		return true
+	})
+	sort.Strings(ids)
+	return ids
+}
+
+// QuitGroup 令适配器对应的骰子账号退出群组，群内的其他骰子账号不受影响
+func (d *Dice) QuitGroup(adapterID string, groupID string) error {
+	adapter, ok := d.GetAdapter(adapterID)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrAdapterNotFound, adapterID)
+	}
+	_, err := adapter.GroupQuit(&adapters.GroupOperationQuitRequest{GroupID: groupID})
+	return err
+}
+
+type pendingQuit struct {
+	adapterID string
+	groupID   string
+	timer     *time.Timer
+}
+
+// ScheduleQuitGroup 在 delay 后调用 QuitGroup，Close 时尚未执行的退群会立即执行，关闭后不再接受新的退群
+func (d *Dice) ScheduleQuitGroup(adapterID string, groupID string, delay time.Duration) {
+	d.pendingQuitMu.Lock()
+	defer d.pendingQuitMu.Unlock()
+	if d.pendingQuitClosed {
+		return
+	}
+	if d.pendingQuits == nil {
+		d.pendingQuits = map[*pendingQuit]struct{}{}
+	}
+	q := &pendingQuit{adapterID: adapterID, groupID: groupID}
+	d.pendingQuits[q] = struct{}{}
+	q.timer = time.AfterFunc(delay, func() {
+		if d.takePendingQuit(q) {
+			defer d.pendingQuitWG.Done()
+			_ = d.QuitGroup(q.adapterID, q.groupID)
+		}
+	})
+}
+
+// takePendingQuit 取出待执行的退群，返回 false 表示已被 Close 接管
+func (d *Dice) takePendingQuit(q *pendingQuit) bool {
+	d.pendingQuitMu.Lock()
+	defer d.pendingQuitMu.Unlock()
+	if _, ok := d.pendingQuits[q]; !ok {
+		return false
+	}
+	delete(d.pendingQuits, q)
+	d.pendingQuitWG.Add(1)
+	return true
+}
+
+// flushPendingQuits 停止全部退群计时器并立即退群，等待正在进行的退群结束
+func (d *Dice) flushPendingQuits() {
+	d.pendingQuitMu.Lock()
+	pending := d.pendingQuits
+	d.pendingQuits = nil
+	d.pendingQuitClosed = true
+	d.pendingQuitMu.Unlock()
+
+	for q := range pending {
+		q.timer.Stop()
+		_ = d.QuitGroup(q.adapterID, q.groupID)
+	}
+	d.pendingQuitWG.Wait()
+}
+
+// SendFile 通过适配器发送文件，messageType 为 group 或 private，targetID 为群号或用户ID
+// 仅注册了发送回调的适配器无法发送文件，返回 ErrSendFileUnsupported
+func (d *Dice) SendFile(adapterID string, messageType string, targetID string, path string) error {
+	entry, ok := d.adapterMap.Load(adapterID)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrAdapterNotFound, adapterID)
+	}
+	if entry.adapter == nil {
+		return ErrSendFileUnsupported
+	}
+	request := &adapters.MessageSendFileRequest{
+		Sender:   &adapters.SimpleUserInfo{},
+		FilePath: path,
+		TargetId: targetID,
+	}
+	var err error
+	switch messageType {
+	case "private":
+		_, err = entry.adapter.MsgSendFileToPerson(request)
+	case "group":
+		_, err = entry.adapter.MsgSendFileToGroup(request)
+	default:
+		err = fmt.Errorf("unsupported message type %q", messageType)
+	}
+	return err
+}
+
+func (d *Dice) deliverReply(msg *types.MsgToReply) error {
+	cfg := d.currentConfig()
+	if cfg.ReplyRoutePolicy == ReplyRouteBroadcast {
+		var errs []error
+		d.adapterMap.Range(func(_ string, entry *adapterEntry) bool {
+			if err := entry.send(msg); err != nil {
+				errs = append(errs, fmt.Errorf("adapter %q: %w", entry.id, err))
+			}
+			return true
+		})
+		d.CallbackForSendMsg.Range(func(id string, send func(msg *types.MsgToReply)) bool {
+			if _, registered := d.adapterMap.Load(id); !registered {
+				send(msg)
+			}
+			return true
+		})
+		return errors.Join(errs...)
+	}
+
+	entry, ok := d.adapterMap.Load(msg.AdapterId)
+	if !ok {
+		// 兼容直接写入 CallbackForSendMsg 的旧代码
+		if send, legacy := d.CallbackForSendMsg.Load(msg.AdapterId); legacy {
+			send(msg)
+			return nil
+		}
+	}
+	if !ok && cfg.ReplyRoutePolicy == ReplyRouteFallback && cfg.DefaultAdapterId != "" {
+		entry, ok = d.adapterMap.Load(cfg.DefaultAdapterId)
+	}
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrAdapterNotFound, msg.AdapterId)
+	}
+	return entry.send(msg)
+}
+
+// dispatchSendFailed 回复投递失败时派发内部事件，避免回复被静默丢弃
+// 处理该事件的钩子不应再向失败的适配器回复
+func (d *Dice) dispatchSendFailed(msg *types.MsgToReply, err error) {
+	d.runEventHooks(msg.AdapterId, &types.AdapterEvent{
+		PostType: EventPostTypeInternal,
+		Type:     EventTypeSendFailed,
+		SubType:  msg.MessageType,
+		Time:     time.Now().Unix(),
+		Platform: msg.SendTo.Platform,
+		GroupID:  msg.SendTo.GroupId,
+		UserID:   msg.SendTo.UserId,
+		Raw: map[string]any{
+			"error":     err.Error(),
+			"adapterId": msg.AdapterId,
+			"commandId": msg.CommandId,
+			"message":   msg.Segments.ToText(),
+		},
+	})
+}
+
+func sendReplyByAdapter(adapter adapters.PlatformAdapter, msg *types.MsgToReply) error {
+	request := &adapters.MessageSendRequest{
+		Segments: msg.Segments,
+	}
+
+	var err error
+	switch msg.MessageType {
+	case "private":
+		request.TargetId = msg.SendTo.UserId
+		_, err = adapter.MsgSendToPerson(request)
+	case "group":
+		request.TargetId = msg.SendTo.GroupId
+		_, err = adapter.MsgSendToGroup(request)
+	default:
+		err = fmt.Errorf("unsupported message type %q", msg.MessageType)
+	}
+	return err
+}
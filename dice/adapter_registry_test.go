@@ -0,0 +1,88 @@
+package dice
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sealdice/smallseal/dice/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendReplyRoutesToOriginAdapter(t *testing.T) {
+	var d Dice
+	as := assert.New(t)
+
+	got := map[string]int{}
+	for _, id := range []string{"ob11", "milky"} {
+		adapterID := id
+		as.NoError(d.RegisterAdapterSender(adapterID, func(*types.MsgToReply) {
+			got[adapterID]++
+		}))
+	}
+	as.Error(d.RegisterAdapterSender("ob11", func(*types.MsgToReply) {}), "duplicated id should be rejected")
+
+	as.NoError(d.SendReply(&types.MsgToReply{AdapterId: "milky"}))
+	as.Equal(map[string]int{"milky": 1}, got)
+
+	err := d.SendReply(&types.MsgToReply{AdapterId: "missing"})
+	as.True(errors.Is(err, ErrAdapterNotFound), "unexpected error %v", err)
+
+	d.Config.ReplyRoutePolicy = ReplyRouteFallback
+	d.Config.DefaultAdapterId = "ob11"
+	as.NoError(d.SendReply(&types.MsgToReply{AdapterId: "missing"}))
+	as.Equal(map[string]int{"milky": 1, "ob11": 1}, got)
+
+	d.Config.ReplyRoutePolicy = ReplyRouteBroadcast
+	as.NoError(d.SendReply(&types.MsgToReply{AdapterId: "ob11"}))
+	as.Equal(map[string]int{"milky": 2, "ob11": 2}, got)
+
+	as.True(d.UnregisterAdapter("milky"))
+	as.Equal([]string{"ob11"}, d.ListAdapters())
+}
+
+func TestSendReplyFailureDispatchesEvent(t *testing.T) {
+	d := NewDice()
+	as := assert.New(t)
+
+	var events []*types.AdapterEvent
+	_, err := d.RegisterEventHook("test", types.HookPriorityNormal, func(_ types.DiceLike, _ string, evt *types.AdapterEvent) types.HookResult {
+		events = append(events, evt)
+		return types.HookResultContinue
+	})
+	as.NoError(err)
+
+	err = d.SendReply(&types.MsgToReply{
+		AdapterId:   "missing",
+		CommandId:   7,
+		MessageType: "group",
+		SendTo:      types.MsgSendToInfo{GroupId: "QQ-Group:1"},
+		Segments:    types.MessageSegments{&types.TextElement{Content: "hi"}},
+	})
+	as.ErrorIs(err, ErrAdapterNotFound)
+	if as.Len(events, 1) {
+		evt := events[0]
+		as.Equal(EventPostTypeInternal, evt.PostType)
+		as.Equal(EventTypeSendFailed, evt.Type)
+		as.Equal("QQ-Group:1", evt.GroupID)
+		as.Equal("missing", evt.Raw["adapterId"])
+		as.Equal("hi", evt.Raw["message"])
+	}
+}
+
+func TestCallbackForSendMsgCompat(t *testing.T) {
+	var d Dice
+	as := assert.New(t)
+
+	as.NoError(d.RegisterAdapterSender("ob11", func(*types.MsgToReply) {}))
+	_, ok := d.CallbackForSendMsg.Load("ob11")
+	as.True(ok, "RegisterAdapterSender fills in the deprecated map")
+
+	legacy := 0
+	d.CallbackForSendMsg.Store("old", func(*types.MsgToReply) { legacy++ })
+	as.NoError(d.SendReply(&types.MsgToReply{AdapterId: "old"}))
+	as.Equal(1, legacy)
+
+	as.True(d.UnregisterAdapter("ob11"))
+	_, ok = d.CallbackForSendMsg.Load("ob11")
+	as.False(ok)
+}
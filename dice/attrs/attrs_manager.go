@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	ds "github.com/sealdice/dicescript"
@@ -16,6 +17,7 @@ import (
 
 type AttrsManager struct {
 	cancel context.CancelFunc
+	done   chan struct{}
 	m      utils.SyncMap[string, *AttributesItem]
 
 	io AttrsIO
@@ -25,12 +27,21 @@ func (am *AttrsManager) SetIO(io AttrsIO) {
 	am.io = io
 }
 
-func (am *AttrsManager) Stop() {
-	am.cancel()
+// Stop 停止定时保存任务，并执行最后一次保存，可重复调用
+func (am *AttrsManager) Stop() error {
+	if am.cancel != nil {
+		am.cancel()
+		<-am.done
+	}
+	if am.io == nil {
+		// 从未载入过数据，无需保存
+		return nil
+	}
+	return am.CheckForSave()
 }
 
 func (am *AttrsManager) Load(groupId string, userId string) (*AttributesItem, error) {
-	userId = am.UIDConvert(userId)
+	userId = am.UIDConvert("", userId)
 
 	am.ensureIO()
 
@@ -52,13 +63,19 @@ func (am *AttrsManager) Load(groupId string, userId string) (*AttributesItem, er
 	return am.LoadById(id)
 }
 
-func (am *AttrsManager) UIDConvert(userId string) string {
+// UIDConvert 将未带平台前缀的用户ID补全为 platform:ID，TG:123 这类已带前缀的ID保持不变
+// platform 应取自消息来源的适配器，见 MsgContext.Platform
+// 各读写方法按传入的ID存取数据，不会自行补全，已有的纯数字ID的数据因此不受影响
+func (am *AttrsManager) UIDConvert(platform string, userId string) string {
+	if platform != "" && userId != "" && !strings.Contains(userId, ":") {
+		userId = platform + ":" + userId
+	}
 	// 如果存在一个虚拟id，那么返回虚拟id，不存在原样返回
 	return userId
 }
 
 func (am *AttrsManager) GetCharacterList(userId string) ([]*AttributesItem, error) {
-	userId = am.UIDConvert(userId)
+	userId = am.UIDConvert("", userId)
 	am.ensureIO()
 	lst, err := am.io.ListByUid(userId)
 	if err != nil {
@@ -82,7 +99,7 @@ func (am *AttrsManager) GetCharacterList(userId string) ([]*AttributesItem, erro
 }
 
 func (am *AttrsManager) CharNew(userId string, name string, sheetType string) (*AttributesItem, error) {
-	userId = am.UIDConvert(userId)
+	userId = am.UIDConvert("", userId)
 	am.ensureIO()
 	dict := &ds.ValueMap{}
 	// dict.Store("$sheetType", ds.NewStrVal(sheetType))
@@ -204,19 +221,17 @@ func (am *AttrsManager) Init() {
 	// am.logger = d.Logger
 	// 创建一个 context 用于取消 goroutine
 	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
 	// 启动后台定时任务
 	go func() {
+		defer close(done)
 		ticker := time.NewTicker(60 * time.Second)
 		defer ticker.Stop()
 
 		for {
 			select {
 			case <-ctx.Done():
-				// 检测到取消信号，执行最后一次保存后退出
-				// d.Logger.Info("正在执行最后一次数据保存...")
-				if err := am.CheckForSave(); err != nil {
-					// d.Logger.Errorf("最终数据保存失败: %v", err)
-				}
+				// 检测到取消信号后退出，最后一次保存由 Stop 完成
 				return
 			case <-ticker.C:
 				// 定时执行保存和清理任务
@@ -230,6 +245,7 @@ func (am *AttrsManager) Init() {
 		}
 	}()
 	am.cancel = cancel
+	am.done = done
 }
 
 func (am *AttrsManager) CheckForSave() error {
@@ -280,7 +296,7 @@ func (am *AttrsManager) CheckAndFreeUnused() error {
 }
 
 func (am *AttrsManager) CharBind(charId string, groupId string, userId string) error {
-	userId = am.UIDConvert(userId)
+	userId = am.UIDConvert("", userId)
 	am.ensureIO()
 	if charId == "" {
 		return am.io.Unbind(groupId, userId)
@@ -288,15 +304,49 @@ func (am *AttrsManager) CharBind(charId string, groupId string, userId string) e
 	return am.io.Bind(groupId, userId, charId)
 }
 
+// GroupDataDelete 删除群内数据: 群属性、群成员的群内默认卡，并解除成员在群内的绑卡
+// 用户自建的角色卡与个人全局属性不受影响
+func (am *AttrsManager) GroupDataDelete(groupId string, userIds []string) error {
+	am.ensureIO()
+
+	// 缓存中未落盘的数据同样需要丢弃
+	prefix := groupId + "-"
+	am.m.Range(func(key string, _ *AttributesItem) bool {
+		if key == groupId || strings.HasPrefix(key, prefix) {
+			am.m.Delete(key)
+		}
+		return true
+	})
+
+	var errs []error
+	ids := []string{groupId}
+	for _, userId := range userIds {
+		userId = am.UIDConvert("", userId)
+		if err := am.io.Unbind(groupId, userId); err != nil {
+			errs = append(errs, err)
+		}
+		ids = append(ids, prefix+userId)
+	}
+	for _, id := range ids {
+		if item, err := am.io.GetById(id); err != nil || item == nil {
+			continue
+		}
+		if err := am.io.DeleteById(id); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // CharGetBindingId 获取当前群绑定的角色ID
 func (am *AttrsManager) CharGetBindingId(groupId string, userId string) (string, error) {
-	userId = am.UIDConvert(userId)
+	userId = am.UIDConvert("", userId)
 	am.ensureIO()
 	return am.io.BindingIdGet(groupId, userId)
 }
 
 func (am *AttrsManager) CharIdGetByName(userId string, name string) (string, error) {
-	userId = am.UIDConvert(userId)
+	userId = am.UIDConvert("", userId)
 	am.ensureIO()
 	item, err := am.io.GetByUidAndName(userId, name)
 	if err != nil {
@@ -0,0 +1,336 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sealdice/smallseal/dice/exts"
+	"github.com/sealdice/smallseal/dice/types"
+	"github.com/sealdice/smallseal/utils"
+)
+
+// AutoQuitConfig 自动退出长期未使用指令的群组
+type AutoQuitConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	InactiveDays int           `yaml:"inactiveDays"` // 连续多少天没有指令视为不活跃
+	NoticeDelay  time.Duration `yaml:"noticeDelay"`  // 发出退群预告后等待多久退群，为0时不预告
+	Interval     time.Duration `yaml:"interval"`     // 检查间隔
+	KeepData     bool          `yaml:"keepData"`     // 退群后保留群组信息与群内属性(仅关闭骰子)，否则删除
+}
+
+func defaultAutoQuitConfig() AutoQuitConfig {
+	return AutoQuitConfig{
+		InactiveDays: 30,
+		NoticeDelay:  24 * time.Hour,
+		Interval:     time.Hour,
+	}
+}
+
+var ErrGroupRangeUnsupported = errors.New("group info manager does not implement GroupInfoRanger")
+
+type autoQuitMark struct {
+	noticedAt    time.Time
+	farewellSent bool // 告别语已发出，但退群失败等待重试
+}
+
+// StartGroupSweeper 启动自动退群任务，按 Config.AutoQuit.Interval 定期检查
+// 配置中未开启时任务照常运行但不做任何事，因此可随配置热更新开关；Close 时自动停止
+func (d *Dice) StartGroupSweeper() error {
+	if _, ok := d.GroupInfoManager.(GroupInfoRanger); !ok {
+		return ErrGroupRangeUnsupported
+	}
+
+	stop := make(chan struct{})
+	d.autoQuitMu.Lock()
+	if d.autoQuitStop != nil {
+		close(d.autoQuitStop)
+	}
+	d.autoQuitStop = stop
+	d.autoQuitMu.Unlock()
+
+	go d.runGroupSweeper(stop)
+	return nil
+}
+
+func (d *Dice) runGroupSweeper(stop <-chan struct{}) {
+	interval := d.currentConfig().AutoQuit.Interval
+	if interval <= 0 {
+		interval = defaultAutoQuitConfig().Interval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if d.currentConfig().AutoQuit.Enabled {
+				_ = d.SweepInactiveGroups(time.Now())
+			}
+			// 检查间隔可能被热更新
+			if next := d.currentConfig().AutoQuit.Interval; next > 0 && next != interval {
+				interval = next
+				ticker.Reset(interval)
+			}
+		}
+	}
+}
+
+// stopGroupSweeper 停止自动退群任务
+func (d *Dice) stopGroupSweeper() {
+	d.autoQuitMu.Lock()
+	defer d.autoQuitMu.Unlock()
+	if d.autoQuitStop != nil {
+		close(d.autoQuitStop)
+		d.autoQuitStop = nil
+	}
+}
+
+// SweepInactiveGroups 立即执行一次不活跃群组检查，不受 Config.AutoQuit.Enabled 影响
+// 超过 InactiveDays 天没有指令的群会先收到预告，NoticeDelay 后仍无指令则发送告别语并退群
+// 每个群的处理结果通过 EventTypeAutoQuit 事件派发，返回值汇总了全部错误
+// 管线运行时每个群的检查在该群的消息队列中进行；群内有多个骰子账号时全部退出
+func (d *Dice) SweepInactiveGroups(now time.Time) error {
+	ranger, ok := d.GroupInfoManager.(GroupInfoRanger)
+	if !ok {
+		return ErrGroupRangeUnsupported
+	}
+	cfg := d.currentConfig()
+	if cfg.AutoQuit.InactiveDays <= 0 {
+		return &ConfigError{Key: "autoQuit.inactiveDays", Msg: "must be positive"}
+	}
+
+	var groups []*types.GroupInfo
+	ranger.Range(func(groupId string, groupInfo *types.GroupInfo) bool {
+		// 私聊共用的空群号不参与检查
+		if groupId != "" && groupInfo != nil {
+			groups = append(groups, groupInfo)
+		}
+		return true
+	})
+
+	var errs []error
+	for _, group := range groups {
+		// 与该群的消息在同一队列中处理，避免和指令同时修改群组数据
+		var err error
+		if shardErr := d.runInShard(autoQuitShardMessage(group), func() {
+			err = d.sweepGroup(group, cfg, now)
+		}); shardErr != nil {
+			err = shardErr
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", group.GroupId, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (d *Dice) sweepGroup(group *types.GroupInfo, cfg Config, now time.Time) error {
+	if group.QuitMarkMaster {
+		return nil
+	}
+
+	last := max(group.RecentDiceSendTime, group.EnteredTime)
+	if last == 0 {
+		// 旧数据没有使用记录，从现在开始计时
+		group.RecentDiceSendTime = now.Unix()
+		return nil
+	}
+
+	inactiveFor := now.Sub(time.Unix(last, 0))
+	mark, marked := d.loadAutoQuitMark(group.GroupId)
+	if inactiveFor < time.Duration(cfg.AutoQuit.InactiveDays)*24*time.Hour {
+		if marked {
+			// 预告后又有人使用了指令
+			d.deleteAutoQuitMark(group.GroupId)
+			group.QuitMarkAutoClean = false
+		}
+		return nil
+	}
+
+	if !marked && cfg.AutoQuit.NoticeDelay > 0 {
+		mctx := d.autoQuitContext(group, cfg)
+		exts.VarSetValueStr(mctx, "$t未使用天数", strconv.Itoa(int(inactiveFor/(24*time.Hour))))
+		exts.VarSetValueStr(mctx, "$t退群倒计时", cfg.AutoQuit.NoticeDelay.String())
+		exts.ReplyGroup(mctx, autoQuitMessage(group), exts.DiceFormatTmpl(mctx, "核心:骰子自动退群预告"))
+
+		d.storeAutoQuitMark(group.GroupId, autoQuitMark{noticedAt: now})
+		group.QuitMarkAutoClean = true
+		d.dispatchAutoQuit(group, "notice", nil)
+		return nil
+	}
+	if marked && now.Sub(mark.noticedAt) < cfg.AutoQuit.NoticeDelay {
+		return nil
+	}
+	if !marked {
+		mark = autoQuitMark{noticedAt: now}
+	}
+
+	if !mark.farewellSent {
+		mctx := d.autoQuitContext(group, cfg)
+		exts.ReplyGroup(mctx, autoQuitMessage(group), exts.DiceFormatTmpl(mctx, "核心:骰子自动退群告别语"))
+		mark.farewellSent = true
+	}
+	d.storeAutoQuitMark(group.GroupId, mark)
+
+	// 单骰多号时群内的每个账号都要退群，已退出的账号从群组信息中移除，失败的下次检查时重试
+	var quitErrs []error
+	for adapterId, diceIDs := range d.autoQuitAccounts(group, cfg) {
+		if err := d.QuitGroup(adapterId, group.GroupId); err != nil {
+			quitErrs = append(quitErrs, err)
+			continue
+		}
+		for _, diceID := range diceIDs {
+			group.RemoveDice(diceID)
+		}
+	}
+	if err := errors.Join(quitErrs...); err != nil {
+		// 保留标记，下次检查时重试退群，不再重复发送告别语
+		d.dispatchAutoQuit(group, "quit", err)
+		return err
+	}
+	d.deleteAutoQuitMark(group.GroupId)
+
+	var err error
+	if cfg.AutoQuit.KeepData {
+		group.Active = false
+		group.DiceIDActiveMap = nil
+		group.DiceIDExistsMap = nil
+		group.QuitMarkAutoClean = false
+		group.UpdatedAtTime = now.Unix()
+		d.GroupInfoManager.Store(group.GroupId, group)
+	} else {
+		var userIds []string
+		if group.Players != nil {
+			group.Players.Range(func(userId string, _ *types.GroupPlayerInfo) bool {
+				userIds = append(userIds, userId)
+				return true
+			})
+		}
+		if d.attrsManager != nil {
+			err = d.attrsManager.GroupDataDelete(group.GroupId, userIds)
+		}
+		d.GroupInfoManager.Delete(group.GroupId)
+	}
+	d.dispatchAutoQuit(group, "quit", err)
+	return err
+}
+
+func (d *Dice) loadAutoQuitMark(groupId string) (autoQuitMark, bool) {
+	d.autoQuitMu.Lock()
+	defer d.autoQuitMu.Unlock()
+	mark, ok := d.autoQuitMarks[groupId]
+	return mark, ok
+}
+
+func (d *Dice) storeAutoQuitMark(groupId string, mark autoQuitMark) {
+	d.autoQuitMu.Lock()
+	defer d.autoQuitMu.Unlock()
+	if d.autoQuitMarks == nil {
+		d.autoQuitMarks = map[string]autoQuitMark{}
+	}
+	d.autoQuitMarks[groupId] = mark
+}
+
+func (d *Dice) deleteAutoQuitMark(groupId string) {
+	d.autoQuitMu.Lock()
+	defer d.autoQuitMu.Unlock()
+	delete(d.autoQuitMarks, groupId)
+}
+
+// autoQuitAccounts 按适配器归类群内记录的骰子账号，没有记录时使用 autoQuitAdapterId
+func (d *Dice) autoQuitAccounts(group *types.GroupInfo, cfg Config) map[string][]string {
+	accounts := map[string][]string{}
+	adapterIds := d.ListAdapters()
+	collect := func(diceID string, _ bool) bool {
+		for _, id := range adapterIds {
+			if (diceID == id || strings.HasPrefix(diceID, id+":")) && !slices.Contains(accounts[id], diceID) {
+				accounts[id] = append(accounts[id], diceID)
+				break
+			}
+		}
+		return true
+	}
+	for _, m := range []*utils.SyncMap[string, bool]{group.DiceIDActiveMap, group.DiceIDExistsMap} {
+		if m != nil {
+			m.Range(collect)
+		}
+	}
+	if len(accounts) == 0 {
+		accounts[d.autoQuitAdapterId(group, cfg)] = nil
+	}
+	return accounts
+}
+
+// autoQuitAdapterId 优先使用最近收到本群消息的适配器，未记录时尝试默认的或唯一的适配器
+func (d *Dice) autoQuitAdapterId(group *types.GroupInfo, cfg Config) string {
+	if group.AdapterId != "" {
+		return group.AdapterId
+	}
+	if cfg.DefaultAdapterId != "" {
+		return cfg.DefaultAdapterId
+	}
+	var found []string
+	for _, id := range d.ListAdapters() {
+		if _, ok := d.GetAdapter(id); ok {
+			found = append(found, id)
+		}
+	}
+	if len(found) == 1 {
+		return found[0]
+	}
+	return ""
+}
+
+func (d *Dice) autoQuitContext(group *types.GroupInfo, cfg Config) *types.MsgContext {
+	mctx := d.baseMsgContext(d.autoQuitAdapterId(group, cfg), cfg)
+	mctx.Group = group
+	mctx.GameSystem, _ = d.gameSystem.Load(group.System)
+	mctx.Player = &types.GroupPlayerInfo{}
+	mctx.IsCurGroupBotOn = group.Active
+	return mctx
+}
+
+func autoQuitMessage(group *types.GroupInfo) *types.Message {
+	return &types.Message{
+		MessageType: "group",
+		GroupID:     group.GroupId,
+		GroupName:   group.GroupName,
+		GuildID:     group.GuildID,
+		ChannelID:   group.ChannelID,
+		Time:        time.Now().Unix(),
+	}
+}
+
+// autoQuitShardMessage 构造与本群消息落在同一管线队列的消息
+func autoQuitShardMessage(group *types.GroupInfo) *types.Message {
+	msg := &types.Message{MessageType: "group", GroupID: group.GroupId}
+	if group.ChannelID != "" {
+		// 频道消息通常不带群号，按子频道分片
+		msg.MessageType = types.MessageSceneChannel
+		msg.GroupID = ""
+		msg.GuildID = group.GuildID
+		msg.ChannelID = group.ChannelID
+	}
+	return msg
+}
+
+func (d *Dice) dispatchAutoQuit(group *types.GroupInfo, stage string, err error) {
+	evt := &types.AdapterEvent{
+		PostType: EventPostTypeInternal,
+		Type:     EventTypeAutoQuit,
+		SubType:  stage,
+		Time:     time.Now().Unix(),
+		GroupID:  group.GroupId,
+		Raw:      map[string]any{"recentDiceSendTime": group.RecentDiceSendTime},
+	}
+	if err != nil {
+		evt.Raw["error"] = err.Error()
+	}
+	d.runEventHooks(group.AdapterId, evt)
+}
@@ -0,0 +1,195 @@
+package dice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ds "github.com/sealdice/dicescript"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sealdice/smallseal/adapters"
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+// quitAdapter 记录发往群内的消息与退群请求
+type quitAdapter struct {
+	adapters.PlatformAdapter
+	sent  map[string][]string
+	quits []string
+}
+
+func (a *quitAdapter) MsgSendToGroup(req *adapters.MessageSendRequest) (bool, error) {
+	groupID := req.TargetId.(string)
+	a.sent[groupID] = append(a.sent[groupID], types.MessageSegments(req.Segments).ToText())
+	return true, nil
+}
+
+func (a *quitAdapter) MsgSendToPerson(*adapters.MessageSendRequest) (bool, error) {
+	return true, nil
+}
+
+func (a *quitAdapter) GroupQuit(req *adapters.GroupOperationQuitRequest) (bool, error) {
+	a.quits = append(a.quits, req.GroupID.(string))
+	return true, nil
+}
+
+func TestSweepInactiveGroups(t *testing.T) {
+	as := assert.New(t)
+
+	d := NewDice()
+	d.Config.AutoQuit = AutoQuitConfig{Enabled: true, InactiveDays: 7, NoticeDelay: time.Hour, Interval: time.Hour}
+	adapter := &quitAdapter{sent: map[string][]string{}}
+	require.NoError(t, d.RegisterAdapter("qq", adapter))
+
+	for _, groupID := range []string{"QQ-Group:1", "QQ-Group:2", "QQ-Group:3"} {
+		d.Execute("qq", &types.Message{
+			MessageType: "group",
+			GroupID:     groupID,
+			Sender:      types.SenderBase{UserID: "QQ:10", Nickname: "tester"},
+			Segments:    types.MessageSegments{&types.TextElement{Content: ".st 力量50"}},
+		})
+	}
+	adapter.sent = map[string][]string{}
+
+	load := func(groupID string) *types.GroupInfo {
+		group, ok := d.GroupInfoManager.Load(groupID)
+		require.True(t, ok)
+		return group
+	}
+	as.Equal("qq", load("QQ-Group:1").AdapterId)
+	as.NotZero(load("QQ-Group:1").RecentDiceSendTime)
+
+	now := time.Now()
+	longAgo := now.Add(-10 * 24 * time.Hour).Unix()
+	load("QQ-Group:1").RecentDiceSendTime = longAgo
+	load("QQ-Group:3").RecentDiceSendTime = longAgo
+
+	require.NoError(t, d.SweepInactiveGroups(now))
+	as.Len(adapter.sent["QQ-Group:1"], 1)
+	as.Contains(adapter.sent["QQ-Group:1"][0], "10天没有使用过指令")
+	as.Len(adapter.sent["QQ-Group:3"], 1)
+	as.Empty(adapter.sent["QQ-Group:2"], "active group is left alone")
+	as.True(load("QQ-Group:1").QuitMarkAutoClean)
+	as.Empty(adapter.quits)
+
+	// 预告后在群内使用指令可以取消退群
+	load("QQ-Group:3").RecentDiceSendTime = now.Unix()
+
+	require.NoError(t, d.SweepInactiveGroups(now.Add(30*time.Minute)))
+	as.Len(adapter.sent["QQ-Group:1"], 1, "no repeated notice before the delay")
+	as.False(load("QQ-Group:3").QuitMarkAutoClean)
+
+	require.NoError(t, d.SweepInactiveGroups(now.Add(2*time.Hour)))
+	as.Equal([]string{"QQ-Group:1"}, adapter.quits)
+	if as.Len(adapter.sent["QQ-Group:1"], 2) {
+		as.Contains(adapter.sent["QQ-Group:1"][1], "由于长时间不使用")
+	}
+
+	_, ok := d.GroupInfoManager.Load("QQ-Group:1")
+	as.False(ok, "group info should be deleted")
+	item, err := d.attrsManager.Load("QQ-Group:1", "QQ:10")
+	require.NoError(t, err)
+	_, exists := item.Load("力量")
+	as.False(exists, "group-scoped attributes should be deleted")
+
+	item, err = d.attrsManager.Load("QQ-Group:2", "QQ:10")
+	require.NoError(t, err)
+	v, exists := item.Load("力量")
+	if as.True(exists) {
+		as.Equal(ds.NewIntVal(50), v)
+	}
+}
+
+func TestSweepInactiveGroupsKeepData(t *testing.T) {
+	as := assert.New(t)
+
+	d := NewDice()
+	d.Config.AutoQuit = AutoQuitConfig{InactiveDays: 7, KeepData: true}
+	adapter := &quitAdapter{sent: map[string][]string{}}
+	require.NoError(t, d.RegisterAdapter("qq", adapter))
+
+	group := d.newGroupInfo("QQ-Group:1", d.Config)
+	group.EnteredTime = time.Now().Add(-8 * 24 * time.Hour).Unix()
+	d.GroupInfoManager.Store(group.GroupId, group)
+
+	// NoticeDelay 为0时不预告，直接告别并退群；未记录适配器时使用唯一的适配器
+	require.NoError(t, d.SweepInactiveGroups(time.Now()))
+	as.Equal([]string{"QQ-Group:1"}, adapter.quits)
+	as.Len(adapter.sent["QQ-Group:1"], 1)
+
+	kept, ok := d.GroupInfoManager.Load("QQ-Group:1")
+	require.True(t, ok)
+	as.False(kept.Active)
+}
+
+func TestSweepInactiveGroupsQuitsEveryAccount(t *testing.T) {
+	as := assert.New(t)
+
+	d := NewDice()
+	d.Config.AutoQuit = AutoQuitConfig{InactiveDays: 7, KeepData: true}
+	first := &quitAdapter{sent: map[string][]string{}}
+	second := &quitAdapter{sent: map[string][]string{}}
+	require.NoError(t, d.RegisterAdapter("qq", first))
+	require.NoError(t, d.RegisterAdapter("qq2", second))
+
+	for _, id := range []string{"qq", "qq2"} {
+		d.Execute(id, &types.Message{
+			MessageType: "group",
+			GroupID:     "QQ-Group:1",
+			SelfID:      "QQ:" + id,
+			Sender:      types.SenderBase{UserID: "QQ:10", Nickname: "tester"},
+			Segments:    types.MessageSegments{&types.TextElement{Content: ".r d20"}},
+		})
+	}
+	group, ok := d.GroupInfoManager.Load("QQ-Group:1")
+	require.True(t, ok)
+	group.RecentDiceSendTime = time.Now().Add(-10 * 24 * time.Hour).Unix()
+
+	// 管线运行时检查在该群的队列中进行
+	require.NoError(t, d.StartPipeline(PipelineConfig{Workers: 2}))
+	require.NoError(t, d.SweepInactiveGroups(time.Now()))
+	as.Equal(uint64(1), d.PipelineStats().Completed)
+	require.NoError(t, d.StopPipeline(context.Background()))
+
+	as.Equal([]string{"QQ-Group:1"}, first.quits)
+	as.Equal([]string{"QQ-Group:1"}, second.quits)
+	as.False(group.Active)
+}
+
+func TestSweepInactiveGroupsDoesNotBlockSweeperControl(t *testing.T) {
+	as := assert.New(t)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	d := newPipelineTestDice(t, func(*types.MsgContext, *types.Message, *types.CmdArgs) {
+		close(started)
+		<-release
+	})
+	d.Config.AutoQuit = AutoQuitConfig{InactiveDays: 7, NoticeDelay: time.Hour, Interval: time.Hour}
+	require.NoError(t, d.StartPipeline(PipelineConfig{Workers: 1}))
+	as.NoError(d.TrySubmit("test", pipelineTestMessage("QQ-Group:1", ".seq")))
+	<-started
+
+	// 检查在等待群的队列时，不影响启停自动退群任务
+	swept := make(chan error, 1)
+	go func() {
+		swept <- d.SweepInactiveGroups(time.Now())
+	}()
+	controlled := make(chan struct{})
+	go func() {
+		as.NoError(d.StartGroupSweeper())
+		d.stopGroupSweeper()
+		close(controlled)
+	}()
+	select {
+	case <-controlled:
+	case <-time.After(time.Second):
+		t.Fatal("sweeper control blocked by a pending sweep")
+	}
+
+	close(release)
+	as.NoError(<-swept)
+	as.NoError(d.StopPipeline(context.Background()))
+}
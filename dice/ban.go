@@ -0,0 +1,16 @@
+package dice
+
+import (
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+// isBannedMessage 检查消息的发送者或所在群是否已被拉黑，master 不受限制
+func (d *Dice) isBannedMessage(msg *types.Message) bool {
+	if d.banManager == nil || d.IsMaster(msg.Sender.UserID) {
+		return false
+	}
+	if d.banManager.IsBanned(msg.Sender.UserID) {
+		return true
+	}
+	return types.IsGroupScene(msg.MessageType) && d.banManager.IsBanned(msg.GroupID)
+}
@@ -0,0 +1,11 @@
+package ban
+
+// BanIO 定义了黑名单数据访问层的接口
+type BanIO interface {
+	// 列出全部黑名单记录，用于启动时载入
+	List() ([]*BanListItem, error)
+	// 批量更新插入记录
+	Puts(items []*BanListItem) error
+	// 根据ID删除记录
+	DeleteById(id string) error
+}
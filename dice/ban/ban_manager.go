@@ -0,0 +1,286 @@
+package ban
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+type BanRank int
+
+const (
+	BanRankBanned  BanRank = -30
+	BanRankWarn    BanRank = -10
+	BanRankNormal  BanRank = 0
+	BanRankTrusted BanRank = 30
+)
+
+func (r BanRank) String() string {
+	switch r {
+	case BanRankBanned:
+		return "禁止"
+	case BanRankWarn:
+		return "警告"
+	case BanRankTrusted:
+		return "信任"
+	default:
+		return "常规"
+	}
+}
+
+// BanListItem 黑名单记录，ID 为用户ID或群组ID
+type BanListItem struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Score     int64    `json:"score"`     // 怒气值
+	Rank      BanRank  `json:"rank"`      // 当前等级
+	Times     []int64  `json:"times"`     // 事发时间
+	Reasons   []string `json:"reasons"`   // 事发原因
+	Places    []string `json:"places"`    // 事发地点
+	BanTime   int64    `json:"banTime"`   // 拉黑时间
+	UpdatedAt int64    `json:"updatedAt"` // 更新时间
+}
+
+func (i *BanListItem) clone() *BanListItem {
+	c := *i
+	c.Times = append([]int64(nil), i.Times...)
+	c.Reasons = append([]string(nil), i.Reasons...)
+	c.Places = append([]string(nil), i.Places...)
+	return &c
+}
+
+// BanConfig 自动拉黑的积分设置，分数为0时对应行为不计分
+type BanConfig struct {
+	ThresholdWarn  int64 `yaml:"thresholdWarn"`  // 怒气值达到后进入警告
+	ThresholdBan   int64 `yaml:"thresholdBan"`   // 怒气值达到后自动拉黑
+	ScoreRateLimit int64 `yaml:"scoreRateLimit"` // 触发刷屏限制一次所加分数
+	ScoreMalicious int64 `yaml:"scoreMalicious"` // 恶意指令一次所加分数
+	DecayPerDay    int64 `yaml:"decayPerDay"`    // 怒气值每天自然降低的分数，降到警告线以下时解除警告，不会解除拉黑
+}
+
+func DefaultBanConfig() BanConfig {
+	return BanConfig{
+		ThresholdWarn:  100,
+		ThresholdBan:   200,
+		ScoreRateLimit: 25,
+		ScoreMalicious: 200,
+		DecayPerDay:    50,
+	}
+}
+
+type BanManager struct {
+	mu     sync.RWMutex
+	config BanConfig
+	items  map[string]*BanListItem
+
+	io BanIO
+}
+
+func NewBanManager() *BanManager {
+	return &BanManager{
+		config: DefaultBanConfig(),
+		items:  map[string]*BanListItem{},
+	}
+}
+
+// Config 返回当前的积分设置
+func (m *BanManager) Config() BanConfig {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.config
+}
+
+// SetConfig 替换积分设置，可在运行中调用
+func (m *BanManager) SetConfig(cfg BanConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config = cfg
+}
+
+// SetIO 设置存储并从中载入已有记录
+func (m *BanManager) SetIO(io BanIO) error {
+	items, err := io.List()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.io = io
+	m.items = make(map[string]*BanListItem, len(items))
+	for _, item := range items {
+		if item != nil && item.ID != "" {
+			m.items[item.ID] = item
+		}
+	}
+	return nil
+}
+
+func (m *BanManager) ensureIO() {
+	if m.io == nil {
+		m.io = NewMemoryBanIO()
+	}
+	if m.items == nil {
+		m.items = map[string]*BanListItem{}
+	}
+}
+
+// Get 获取记录的副本
+func (m *BanManager) Get(id string) (*BanListItem, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	item, ok := m.items[id]
+	if !ok {
+		return nil, false
+	}
+	return item.clone(), true
+}
+
+// IsBanned 检查ID是否处于禁止等级
+func (m *BanManager) IsBanned(id string) bool {
+	return m.RankOf(id) == BanRankBanned
+}
+
+// RankOf 返回ID当前的等级，不存在时为常规
+func (m *BanManager) RankOf(id string) BanRank {
+	if id == "" {
+		return BanRankNormal
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if item, ok := m.items[id]; ok {
+		return item.Rank
+	}
+	return BanRankNormal
+}
+
+// AddScore 为ID增加怒气值，达到阈值时自动升级为警告或拉黑
+// 返回更新后的记录，以及等级是否发生了变化
+func (m *BanManager) AddScore(id string, score int64, place string, reason string) (*BanListItem, bool, error) {
+	if id == "" || score == 0 {
+		return nil, false, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ensureIO()
+
+	item := m.loadForUpdateLocked(id)
+	if item.Rank == BanRankTrusted {
+		return item, false, nil
+	}
+	prevRank := item.Rank
+	m.decayLocked(item, time.Now().Unix())
+	item.Score += score
+	m.appendEventLocked(item, place, reason)
+
+	switch {
+	case m.config.ThresholdBan > 0 && item.Score >= m.config.ThresholdBan:
+		item.Rank = BanRankBanned
+	case m.config.ThresholdWarn > 0 && item.Score >= m.config.ThresholdWarn && item.Rank != BanRankBanned:
+		item.Rank = BanRankWarn
+	}
+	if item.Rank == BanRankBanned && prevRank != BanRankBanned {
+		item.BanTime = item.UpdatedAt
+	}
+
+	if err := m.io.Puts([]*BanListItem{item}); err != nil {
+		return nil, false, err
+	}
+	m.items[id] = item
+	return item.clone(), prevRank != item.Rank, nil
+}
+
+// SetRank 直接设置ID的等级，用于手动拉黑或信任
+func (m *BanManager) SetRank(id string, name string, rank BanRank, place string, reason string) (*BanListItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ensureIO()
+
+	item := m.loadForUpdateLocked(id)
+	if name != "" {
+		item.Name = name
+	}
+	item.Rank = rank
+	m.appendEventLocked(item, place, reason)
+	if rank == BanRankBanned {
+		item.BanTime = item.UpdatedAt
+	}
+
+	if err := m.io.Puts([]*BanListItem{item}); err != nil {
+		return nil, err
+	}
+	m.items[id] = item
+	return item.clone(), nil
+}
+
+// Delete 移除ID的记录，同时清空怒气值
+func (m *BanManager) Delete(id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ensureIO()
+
+	if _, ok := m.items[id]; !ok {
+		return false, nil
+	}
+	if err := m.io.DeleteById(id); err != nil {
+		return false, err
+	}
+	delete(m.items, id)
+	return true, nil
+}
+
+// List 列出指定等级的记录，不传等级时列出全部，结果按ID排序
+func (m *BanManager) List(ranks ...BanRank) []*BanListItem {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	items := make([]*BanListItem, 0, len(m.items))
+	for _, item := range m.items {
+		if len(ranks) > 0 && !slices.Contains(ranks, item.Rank) {
+			continue
+		}
+		items = append(items, item.clone())
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ID < items[j].ID
+	})
+	return items
+}
+
+// loadForUpdateLocked 返回记录的副本用于修改，写入存储成功后再放回 items，失败时内存中的记录保持不变
+func (m *BanManager) loadForUpdateLocked(id string) *BanListItem {
+	if item, ok := m.items[id]; ok {
+		return item.clone()
+	}
+	return &BanListItem{ID: id, Rank: BanRankNormal}
+}
+
+// decayLocked 按上次更新以来经过的时间降低怒气值，在下次计分时结算，警告等级随之解除
+func (m *BanManager) decayLocked(item *BanListItem, now int64) {
+	if m.config.DecayPerDay <= 0 || item.UpdatedAt == 0 || item.Score <= 0 {
+		return
+	}
+	decay := (now - item.UpdatedAt) * m.config.DecayPerDay / int64(24*time.Hour/time.Second)
+	if decay <= 0 {
+		return
+	}
+	item.Score = max(item.Score-decay, 0)
+	if item.Rank == BanRankWarn && (m.config.ThresholdWarn <= 0 || item.Score < m.config.ThresholdWarn) {
+		item.Rank = BanRankNormal
+	}
+}
+
+func (m *BanManager) appendEventLocked(item *BanListItem, place string, reason string) {
+	now := time.Now().Unix()
+	item.UpdatedAt = now
+	if reason == "" {
+		return
+	}
+	item.Times = append(item.Times, now)
+	item.Reasons = append(item.Reasons, reason)
+	item.Places = append(item.Places, place)
+}
@@ -0,0 +1,54 @@
+package ban
+
+import (
+	"errors"
+	"sync"
+)
+
+// MemoryBanIO 基于内存的BanIO实现
+type MemoryBanIO struct {
+	mu    sync.RWMutex
+	items map[string]*BanListItem
+}
+
+// NewMemoryBanIO 创建新的内存BanIO实例
+func NewMemoryBanIO() *MemoryBanIO {
+	return &MemoryBanIO{
+		items: make(map[string]*BanListItem),
+	}
+}
+
+// List 列出全部黑名单记录
+func (m *MemoryBanIO) List() ([]*BanListItem, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	items := make([]*BanListItem, 0, len(m.items))
+	for _, item := range m.items {
+		items = append(items, item.clone())
+	}
+	return items, nil
+}
+
+// Puts 批量更新插入记录
+func (m *MemoryBanIO) Puts(items []*BanListItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, item := range items {
+		if item == nil || item.ID == "" {
+			return errors.New("id cannot be empty")
+		}
+		m.items[item.ID] = item.clone()
+	}
+	return nil
+}
+
+// DeleteById 根据ID删除记录
+func (m *MemoryBanIO) DeleteById(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.items, id)
+	return nil
+}
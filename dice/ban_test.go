@@ -0,0 +1,135 @@
+package dice
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sealdice/smallseal/dice/ban"
+)
+
+func TestBanCommandBlocksSender(t *testing.T) {
+	as := assert.New(t)
+
+	d := newTestDice(t, nil)
+	d.MasterAdd("QQ:master")
+	send := func(userID string, content string) string {
+		return d.send(groupMessage(userID, userID, "", content))
+	}
+
+	as.Equal("你没有权限这样做", send("QQ:user", ".ban add QQ:master"))
+
+	send("QQ:master", ".ban add QQ:user 测试")
+	as.True(d.BanManager().IsBanned("QQ:user"))
+	as.Empty(send("QQ:user", ".r d20"), "banned user should be ignored")
+
+	send("QQ:master", ".ban rm QQ:user")
+	as.NotEmpty(send("QQ:user", ".r d20"))
+}
+
+func TestBanScoreEscalation(t *testing.T) {
+	as := assert.New(t)
+
+	m := ban.NewBanManager()
+	m.SetConfig(ban.BanConfig{ThresholdWarn: 10, ThresholdBan: 20})
+
+	item, changed, err := m.AddScore("QQ:1", 10, "QQ-Group:1", "刷屏")
+	as.NoError(err)
+	as.True(changed)
+	as.Equal(ban.BanRankWarn, item.Rank)
+
+	item, changed, err = m.AddScore("QQ:1", 10, "QQ-Group:1", "刷屏")
+	as.NoError(err)
+	as.True(changed)
+	as.Equal(ban.BanRankBanned, item.Rank)
+	as.Len(item.Reasons, 2)
+
+	_, err = m.SetRank("QQ:2", "", ban.BanRankTrusted, "", "")
+	as.NoError(err)
+	_, changed, err = m.AddScore("QQ:2", 100, "QQ-Group:1", "刷屏")
+	as.NoError(err)
+	as.False(changed, "trusted users never escalate")
+}
+
+func TestBanConfigFromConfig(t *testing.T) {
+	as := assert.New(t)
+
+	cfg, err := ParseConfig([]byte("ban:\n  thresholdWarn: 5\n  thresholdBan: 0\n"))
+	as.NoError(err)
+	as.Equal(int64(25), cfg.Ban.ScoreRateLimit, "missing keys keep defaults")
+
+	d := newTestDice(t, cfg)
+	as.Equal(int64(5), d.BanManager().Config().ThresholdWarn)
+	as.Equal(int64(0), d.BanManager().Config().ThresholdBan)
+
+	_, err = ParseConfig([]byte("ban:\n  scoreMalicious: -1\n"))
+	as.ErrorContains(err, "ban.scoreMalicious")
+}
+
+// stubBanIO 载入给定的记录，failPuts 为 true 时写入总是失败
+type stubBanIO struct {
+	items    []*ban.BanListItem
+	failPuts bool
+}
+
+func (s *stubBanIO) List() ([]*ban.BanListItem, error) { return s.items, nil }
+
+func (s *stubBanIO) Puts([]*ban.BanListItem) error {
+	if s.failPuts {
+		return errors.New("disk full")
+	}
+	return nil
+}
+
+func (s *stubBanIO) DeleteById(string) error { return nil }
+
+func TestBanScoreKeptOnWriteFailure(t *testing.T) {
+	as := assert.New(t)
+
+	m := ban.NewBanManager()
+	m.SetConfig(ban.BanConfig{ThresholdWarn: 10, ThresholdBan: 20})
+	as.NoError(m.SetIO(&stubBanIO{
+		items:    []*ban.BanListItem{{ID: "QQ:1", Score: 15, Rank: ban.BanRankWarn}},
+		failPuts: true,
+	}))
+
+	_, _, err := m.AddScore("QQ:1", 10, "QQ-Group:1", "刷屏")
+	as.ErrorContains(err, "disk full")
+	_, err = m.SetRank("QQ:2", "", ban.BanRankBanned, "", "手动")
+	as.ErrorContains(err, "disk full")
+
+	item, ok := m.Get("QQ:1")
+	as.True(ok)
+	as.Equal(int64(15), item.Score)
+	as.Equal(ban.BanRankWarn, item.Rank)
+	as.Empty(item.Reasons)
+	_, ok = m.Get("QQ:2")
+	as.False(ok)
+}
+
+func TestBanScoreDecay(t *testing.T) {
+	as := assert.New(t)
+
+	m := ban.NewBanManager()
+	m.SetConfig(ban.BanConfig{ThresholdWarn: 100, ThresholdBan: 200, DecayPerDay: 50})
+	twoDaysAgo := time.Now().Add(-48 * time.Hour).Unix()
+	as.NoError(m.SetIO(&stubBanIO{items: []*ban.BanListItem{
+		{ID: "QQ:1", Score: 150, Rank: ban.BanRankWarn, UpdatedAt: twoDaysAgo},
+		{ID: "QQ:2", Score: 250, Rank: ban.BanRankBanned, UpdatedAt: twoDaysAgo},
+	}}))
+
+	// 两天降低100，回到警告线以下即解除警告
+	item, changed, err := m.AddScore("QQ:1", 1, "QQ-Group:1", "刷屏")
+	as.NoError(err)
+	as.True(changed)
+	as.Equal(int64(51), item.Score)
+	as.Equal(ban.BanRankNormal, item.Rank)
+
+	// 拉黑不会因为降分而解除
+	item, _, err = m.AddScore("QQ:2", 1, "QQ-Group:1", "刷屏")
+	as.NoError(err)
+	as.Equal(int64(151), item.Score)
+	as.Equal(ban.BanRankBanned, item.Rank)
+}
@@ -0,0 +1,72 @@
+package dice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var ErrDiceClosed = errors.New("dice closed")
+
+// beginExecute 登记一次执行，已关闭时返回 false
+func (d *Dice) beginExecute() bool {
+	d.closeMu.RLock()
+	defer d.closeMu.RUnlock()
+	if d.closed {
+		return false
+	}
+	d.inflight.Add(1)
+	return true
+}
+
+// Close 停止接收消息，等待执行中的指令结束，保存数据并停止后台任务
+// 可重复调用，之后的调用返回首次关闭的结果
+func (d *Dice) Close(ctx context.Context) error {
+	d.closeOnce.Do(func() {
+		d.closeErr = d.close(ctx)
+	})
+	return d.closeErr
+}
+
+func (d *Dice) close(ctx context.Context) error {
+	var errs []error
+
+	d.closeMu.Lock()
+	d.closed = true
+	d.closeMu.Unlock()
+	d.stopConfigWatch()
+	d.stopGroupSweeper()
+
+	if err := d.StopPipeline(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("排空执行队列: %w", err))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		d.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("等待执行中的指令: %w", ctx.Err()))
+	}
+	d.flushPendingQuits()
+
+	if d.attrsManager != nil {
+		if err := d.attrsManager.Stop(); err != nil {
+			errs = append(errs, fmt.Errorf("保存属性数据: %w", err))
+		}
+	}
+	if flusher, ok := d.GroupInfoManager.(GroupInfoFlusher); ok {
+		if err := flusher.Flush(); err != nil {
+			errs = append(errs, fmt.Errorf("保存群组信息: %w", err))
+		}
+	}
+
+	d.inboundHooks.clear()
+	d.outboundHooks.clear()
+	d.eventHooks.clear()
+
+	return errors.Join(errs...)
+}
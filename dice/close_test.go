@@ -0,0 +1,81 @@
+package dice
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sealdice/smallseal/dice/attrs"
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+type countingAttrsIO struct {
+	*attrs.MemoryAttrsIO
+	puts int
+}
+
+func (c *countingAttrsIO) Puts(items []*attrs.AttrsUpsertParams) error {
+	c.puts += len(items)
+	return c.MemoryAttrsIO.Puts(items)
+}
+
+type flushingGroupInfoManager struct {
+	*DefaultGroupInfoManager
+	flushes int
+	err     error
+}
+
+func (m *flushingGroupInfoManager) Flush() error {
+	m.flushes++
+	return m.err
+}
+
+func TestCloseFlushesAndStopsIntake(t *testing.T) {
+	as := assert.New(t)
+
+	d := newTestDice(t, nil)
+	io := &countingAttrsIO{MemoryAttrsIO: attrs.NewMemoryAttrsIO()}
+	d.AttrsSetIO(io)
+	groups := &flushingGroupInfoManager{DefaultGroupInfoManager: NewDefaultGroupInfoManager(), err: errors.New("disk full")}
+	d.GroupInfoManager = groups
+	as.NoError(d.StartPipeline(PipelineConfig{Workers: 1}))
+
+	msg := func(content string) *types.Message {
+		return groupMessage("QQ:1", "tester", "", content)
+	}
+	as.NoError(d.Submit(context.Background(), "test", msg(".st 力量50")))
+
+	err := d.Close(context.Background())
+	as.ErrorContains(err, "disk full")
+	as.Equal(1, groups.flushes)
+	as.Positive(io.puts, "unsaved attributes should be written on close")
+
+	// 重复关闭返回相同结果，不再重复落盘
+	as.Equal(err, d.Close(context.Background()))
+	as.Equal(1, groups.flushes)
+
+	as.ErrorIs(d.exec(msg(".r d20")).Err, ErrDiceClosed)
+	as.ErrorIs(d.StartPipeline(PipelineConfig{}), ErrDiceClosed)
+	as.ErrorIs(d.TrySubmit("test", msg(".r d20")), ErrPipelineNotStarted)
+}
+
+func TestCloseRunsPendingQuits(t *testing.T) {
+	as := assert.New(t)
+
+	d := newTestDice(t, nil)
+	adapter := &quitAdapter{sent: map[string][]string{}}
+	as.NoError(d.RegisterAdapter("qq", adapter))
+
+	d.ScheduleQuitGroup("qq", "QQ-Group:1", time.Hour)
+	as.Empty(adapter.quits)
+
+	// 关闭时立即执行尚未到时的退群，之后不再接受新的退群
+	as.NoError(d.Close(context.Background()))
+	as.Equal([]string{"QQ-Group:1"}, adapter.quits)
+	d.ScheduleQuitGroup("qq", "QQ-Group:2", 0)
+	time.Sleep(20 * time.Millisecond)
+	as.Equal([]string{"QQ-Group:1"}, adapter.quits)
+}
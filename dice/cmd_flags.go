@@ -0,0 +1,49 @@
+package dice
+
+import (
+	"strings"
+
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+// cmdFlagsAllow 按 CmdItemInfo 的标记判断指令能否进入 solve，不满足时静默跳过
+//
+// 默认模式: 需要当前群开启(私聊总是开启)，或骰子是第一个被@的对象；第一个被@的是其他骰子时跳过。
+// Raw 模式: 不检查上述条件，由 CheckCurrentBotOn、CheckMentionOthers 分别开启对应检查。
+func cmdFlagsAllow(mctx *types.MsgContext, cmdArgs *types.CmdArgs, cmd *types.CmdItemInfo) bool {
+	botOn := mctx.IsPrivate || mctx.Group == nil || mctx.IsCurGroupBotOn
+	if !cmd.Raw {
+		return (botOn || cmdArgs.AmIBeMentionedFirst) && !mentionedOtherDiceFirst(mctx, cmdArgs)
+	}
+	if cmd.CheckCurrentBotOn && !botOn {
+		return false
+	}
+	if cmd.CheckMentionOthers && mentionedOtherDiceFirst(mctx, cmdArgs) {
+		return false
+	}
+	return true
+}
+
+// mentionedOtherDiceFirst 第一个被@的是否为其他骰子：群内标记的机器人(.botlist)，或同一 Dice 的其他骰子账号
+func mentionedOtherDiceFirst(mctx *types.MsgContext, cmdArgs *types.CmdArgs) bool {
+	if len(cmdArgs.At) == 0 || cmdArgs.AmIBeMentionedFirst || mctx.Group == nil {
+		return false
+	}
+	uid := cmdArgs.At[0].UserID
+	if mctx.Group.BotList != nil {
+		if isBot, ok := mctx.Group.BotList.Load(uid); ok && isBot {
+			return true
+		}
+	}
+	found := false
+	if mctx.Group.DiceIDExistsMap != nil {
+		mctx.Group.DiceIDExistsMap.Range(func(diceID string, exists bool) bool {
+			if exists && diceID != mctx.DiceID && strings.HasSuffix(diceID, ":"+uid) {
+				found = true
+				return false
+			}
+			return true
+		})
+	}
+	return found
+}
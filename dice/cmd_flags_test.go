@@ -0,0 +1,64 @@
+package dice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sealdice/smallseal/dice/exts"
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+func TestCmdItemFlags(t *testing.T) {
+	as := assert.New(t)
+	d := newTestDice(t, nil)
+	d.RegisterExtension(&types.ExtInfo{
+		Name:       "rawtest",
+		AutoActive: true,
+		CmdMap: types.CmdMapCls{
+			"ping": &types.CmdItemInfo{
+				Name: "ping",
+				Raw:  true,
+				Solve: func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs) types.CmdExecuteResult {
+					exts.ReplyToSender(ctx, msg, "pong")
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				},
+			},
+		},
+	})
+
+	send := func(messageType string, segments ...types.IMessageElement) []string {
+		msg := &types.Message{
+			Platform:    "QQ",
+			MessageType: messageType,
+			SelfID:      "QQ:999",
+			Sender:      types.SenderBase{UserID: "QQ:1", Nickname: "admin", GroupRole: "admin"},
+			Segments:    segments,
+		}
+		if messageType == "group" {
+			msg.GroupID = "QQ-Group:1"
+		}
+		d.exec(msg)
+		return d.texts()
+	}
+	text := func(s string) types.IMessageElement { return &types.TextElement{Content: s} }
+	at := func(id string) types.IMessageElement { return &types.AtElement{Target: id} }
+
+	as.Equal([]string{"该指令只在群组中可用"}, send("private", text(".welcome on")))
+
+	send("group", text(".bot off"))
+	as.Empty(send("group", text(".r d1")), "group is off")
+	as.Len(send("group", at("999"), text(" .r d1")), 1, "mentioned first while off")
+	as.Empty(send("group", at("123"), at("999"), text(" .r d1")), "mentioned but not first")
+	as.Equal([]string{"pong"}, send("group", text(".ping")), "raw command ignores bot off")
+	as.Empty(send("group", text(".botlist add QQ:555")), "botlist checks current bot on")
+
+	send("group", text(".bot on"))
+	as.Len(send("group", text(".r d1")), 1)
+	send("group", text(".botlist add QQ:555"))
+	as.Empty(send("group", at("555"), text(" .r d1")), "another dice is mentioned first")
+	as.Empty(send("group", at("555"), text(" .bot off")))
+	as.Len(send("group", text(".r d1")), 1, "bot off for the other dice is ignored")
+	as.Equal([]string{"pong"}, send("group", at("555"), text(" .ping")), "raw command without CheckMentionOthers")
+	as.Len(send("group", at("123"), text(" .r d1")), 1, "mentioning a player is fine")
+}
@@ -0,0 +1,62 @@
+package dice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sealdice/smallseal/dice/exts"
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+func TestCommandOverride(t *testing.T) {
+	as := assert.New(t)
+	d := newTestDice(t, nil)
+
+	var seen []string
+	d.RegisterExtension(&types.ExtInfo{
+		Name:       "houserule",
+		AutoActive: true,
+		OnCommandOverride: func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs) bool {
+			seen = append(seen, cmdArgs.Command)
+			switch cmdArgs.Command {
+			case "ra":
+				exts.ReplyToSender(ctx, msg, "房规检定")
+				return true
+			case "rc":
+				panic("boom")
+			}
+			return false
+		},
+	})
+
+	send := func(content string) *ExecuteResult {
+		return d.exec(groupMessage("QQ:1", "admin", "admin", content))
+	}
+
+	result := send(".ra 50")
+	as.True(result.Solved)
+	as.Equal("houserule", result.ExtName)
+	as.Equal("ra", result.Command)
+	if as.Len(d.replies, 1) {
+		as.Equal("房规检定", d.replies[0].Segments.ToText())
+	}
+
+	result = send(".r d1")
+	as.Equal("core", result.ExtName, "commands not overridden are dispatched normally")
+	as.NotEmpty(d.replies)
+
+	result = send(".rc 50")
+	as.True(result.Solved)
+	as.Error(result.Err)
+	as.Equal("houserule", result.ExtName)
+
+	send("hello")
+	as.Equal([]string{"ra", "r", "rc"}, seen, "override only runs for commands")
+
+	send(".ext off houserule")
+	seen = nil
+	result = send(".ra 50")
+	as.Equal("coc7", result.ExtName)
+	as.Empty(seen)
+}
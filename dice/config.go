@@ -0,0 +1,387 @@
+package dice
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sealdice/smallseal/dice/ban"
+	"github.com/sealdice/smallseal/dice/exts"
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+// Config 骰子配置，可由 LoadConfigFile 从 YAML/JSON 文件读取
+// 启动前可直接修改 Dice.Config，运行中请使用 ApplyConfig 或 WatchConfigFile
+type Config struct {
+	CommandPrefix   []string `yaml:"commandPrefix"`   // 指令前缀
+	PlatformPrefix  string   `yaml:"platformPrefix"`  // 默认平台前缀，用于拼接 QQ:123 形式的ID，适配器声明了平台时以适配器为准，热更新时不生效
+	DefaultSystem   string   `yaml:"defaultSystem"`   // 新群默认规则模板
+	OpCountLimit    int64    `yaml:"opCountLimit"`    // 单次表达式求值的算力上限
+	MaxExecuteTimes int      `yaml:"maxExecuteTimes"` // 指令 N# 多轮执行的最大次数，为0时不限制
+	Masters         []string `yaml:"masters"`         // 骰主列表
+
+	ExtConflictReject bool `yaml:"extConflictReject"` // 开启扩展时若与已开启的扩展互斥则拒绝，否则关闭互斥的扩展
+
+	ReplyRoutePolicy ReplyRoutePolicy `yaml:"replyRoutePolicy"` // 回复投递策略
+	DefaultAdapterId string           `yaml:"defaultAdapterId"` // ReplyRouteFallback 下的兜底适配器
+
+	RateLimit RateLimitConfig `yaml:"rateLimit"` // 指令限速
+	Ban       ban.BanConfig   `yaml:"ban"`       // 怒气值与自动拉黑
+
+	ExecuteTimeout time.Duration `yaml:"executeTimeout"` // 单条指令或钩子的执行超时，默认为0即不限制
+
+	RequestPolicy RequestPolicyConfig `yaml:"requestPolicy"` // 好友申请、入群邀请的处理策略
+
+	AutoQuit AutoQuitConfig `yaml:"autoQuit"` // 自动退出不活跃群组
+
+	CustomReplyFile string `yaml:"customReplyFile"` // 自定义回复规则文件(YAML)，为空时规则只保存在内存中
+	HelpdocDir      string `yaml:"helpdocDir"`      // 帮助文档目录，一级目录为文档包
+	LogExportDir    string `yaml:"logExportDir"`    // .log 导出文件的存放目录，为空时使用系统临时目录
+}
+
+const (
+	defaultOpCountLimit    = 30000
+	defaultMaxExecuteTimes = 12
+)
+
+// DefaultConfig 返回默认配置，配置文件中缺省的项使用这里的值
+func DefaultConfig() Config {
+	return Config{
+		CommandPrefix:   []string{".", "。"},
+		PlatformPrefix:  "QQ",
+		DefaultSystem:   "coc7",
+		OpCountLimit:    defaultOpCountLimit,
+		MaxExecuteTimes: defaultMaxExecuteTimes,
+		RateLimit:       defaultRateLimitConfig(),
+		Ban:             ban.DefaultBanConfig(),
+		RequestPolicy: RequestPolicyConfig{
+			Friend:      RequestPolicy{Mode: RequestPolicyManual},
+			GroupInvite: RequestPolicy{Mode: RequestPolicyManual},
+		},
+		AutoQuit: defaultAutoQuitConfig(),
+	}
+}
+
+// ConfigError 配置校验错误，Key 为出错的配置项
+type ConfigError struct {
+	Key string
+	Msg string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("config %s: %s", e.Key, e.Msg)
+}
+
+// Validate 检查配置是否合法，返回全部出错项
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(key string, format string, args ...any) {
+		errs = append(errs, &ConfigError{Key: key, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	if len(c.CommandPrefix) == 0 {
+		add("commandPrefix", "must not be empty")
+	}
+	for i, prefix := range c.CommandPrefix {
+		if strings.TrimSpace(prefix) == "" {
+			add(fmt.Sprintf("commandPrefix[%d]", i), "must not be blank")
+		}
+	}
+	if c.PlatformPrefix == "" {
+		add("platformPrefix", "must not be empty")
+	} else if strings.Contains(c.PlatformPrefix, ":") {
+		add("platformPrefix", "must not contain ':'")
+	}
+	if c.DefaultSystem == "" {
+		add("defaultSystem", "must not be empty")
+	}
+	if c.OpCountLimit < 1 {
+		add("opCountLimit", "must be at least 1, got %d", c.OpCountLimit)
+	}
+	if c.MaxExecuteTimes < 0 {
+		add("maxExecuteTimes", "must not be negative, got %d", c.MaxExecuteTimes)
+	}
+	for i, uid := range c.Masters {
+		if strings.TrimSpace(uid) == "" {
+			add(fmt.Sprintf("masters[%d]", i), "must not be blank")
+		}
+	}
+	if c.ReplyRoutePolicy == ReplyRouteFallback && c.DefaultAdapterId == "" {
+		add("defaultAdapterId", "required when replyRoutePolicy is fallback")
+	}
+	if c.RateLimit.Enabled {
+		if c.RateLimit.PersonalReplenishRate <= 0 {
+			add("rateLimit.personalReplenishRate", "must be positive")
+		}
+		if c.RateLimit.PersonalBurst <= 0 {
+			add("rateLimit.personalBurst", "must be positive")
+		}
+		if c.RateLimit.GroupReplenishRate <= 0 {
+			add("rateLimit.groupReplenishRate", "must be positive")
+		}
+		if c.RateLimit.GroupBurst <= 0 {
+			add("rateLimit.groupBurst", "must be positive")
+		}
+	}
+	for _, item := range []struct {
+		key   string
+		value int64
+	}{
+		{"ban.thresholdWarn", c.Ban.ThresholdWarn},
+		{"ban.thresholdBan", c.Ban.ThresholdBan},
+		{"ban.scoreRateLimit", c.Ban.ScoreRateLimit},
+		{"ban.scoreMalicious", c.Ban.ScoreMalicious},
+		{"ban.decayPerDay", c.Ban.DecayPerDay},
+	} {
+		if item.value < 0 {
+			add(item.key, "must not be negative, got %d", item.value)
+		}
+	}
+	if c.ExecuteTimeout < 0 {
+		add("executeTimeout", "must not be negative, got %s", c.ExecuteTimeout)
+	}
+	for _, item := range []struct {
+		key    string
+		policy RequestPolicy
+	}{
+		{"requestPolicy.friend", c.RequestPolicy.Friend},
+		{"requestPolicy.groupInvite", c.RequestPolicy.GroupInvite},
+	} {
+		key, policy := item.key, item.policy
+		switch policy.Mode {
+		case "", RequestPolicyManual, RequestPolicyAccept, RequestPolicyMasterOnly, RequestPolicyReject:
+		case RequestPolicyPassphrase:
+			if strings.TrimSpace(policy.Passphrase) == "" {
+				add(key+".passphrase", "required when mode is passphrase")
+			}
+		default:
+			add(key+".mode", "unknown mode %q, expect manual/accept/master/passphrase/reject", policy.Mode)
+		}
+	}
+	if c.AutoQuit.Enabled {
+		if c.AutoQuit.InactiveDays <= 0 {
+			add("autoQuit.inactiveDays", "must be positive")
+		}
+		if c.AutoQuit.NoticeDelay < 0 {
+			add("autoQuit.noticeDelay", "must not be negative, got %s", c.AutoQuit.NoticeDelay)
+		}
+		if c.AutoQuit.Interval <= 0 {
+			add("autoQuit.interval", "must be positive")
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ParseConfig 解析配置内容，JSON 是 YAML 的子集，因此两种格式均可
+// 未出现的配置项保留默认值，出现未知配置项时报错
+func ParseConfig(data []byte) (*Config, error) {
+	cfg := DefaultConfig()
+	if len(bytes.TrimSpace(data)) > 0 {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// LoadConfigFile 从 YAML/JSON 文件读取配置
+func LoadConfigFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
+
+// ApplyConfig 校验并应用配置，可在运行中调用，失败时保持原配置
+func (d *Dice) ApplyConfig(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if _, ok := d.gameSystem.Load(cfg.DefaultSystem); !ok {
+		return &ConfigError{Key: "defaultSystem", Msg: fmt.Sprintf("unknown game system %q", cfg.DefaultSystem)}
+	}
+	// 每次应用配置都重新读取规则文件，文件不存在时视为没有规则
+	var replyCfg *exts.ReplyConfig
+	if cfg.CustomReplyFile != "" {
+		var err error
+		if replyCfg, err = exts.LoadReplyConfig(cfg.CustomReplyFile); err != nil {
+			return &ConfigError{Key: "customReplyFile", Msg: err.Error()}
+		}
+	}
+	// 帮助文档只在目录变化时读取，内容更新请使用 .helpdoc reload
+	if cfg.HelpdocDir != "" && cfg.HelpdocDir != d.helpManager.Dir() {
+		if err := d.helpManager.LoadDir(cfg.HelpdocDir); err != nil {
+			return &ConfigError{Key: "helpdocDir", Msg: err.Error()}
+		}
+	}
+	cfg.CommandPrefix = append([]string(nil), cfg.CommandPrefix...)
+	cfg.Masters = append([]string(nil), cfg.Masters...)
+
+	d.configMu.Lock()
+	prevMasters := d.configMasters
+	d.Config = cfg
+	d.configMasters = cfg.Masters
+	d.configMu.Unlock()
+	d.banManager.SetConfig(cfg.Ban)
+	if replyCfg != nil {
+		_ = d.replyStore.Set(cfg.CustomReplyFile, replyCfg)
+	} else {
+		// 不再使用规则文件时保留已有规则，之后的修改不再写回
+		d.replyStore.SetPath("")
+	}
+
+	// 只撤销上次由配置文件添加的骰主，运行中通过指令添加的保持不变
+	for _, uid := range prevMasters {
+		d.MasterRemove(uid)
+	}
+	for _, uid := range cfg.Masters {
+		d.MasterAdd(uid)
+	}
+	return nil
+}
+
+// currentConfig 获取配置快照，运行中读取配置时应使用此方法
+func (d *Dice) currentConfig() Config {
+	d.configMu.RLock()
+	defer d.configMu.RUnlock()
+	return d.Config
+}
+
+// WatchConfigFile 读取并应用配置文件，之后按 interval 轮询文件变化并热更新
+// 更新失败时保持原配置，并派发 EventTypeConfigReload 事件，Raw["error"] 为错误信息
+// 只能在启动时设置的配置项(如 platformPrefix)在热更新时保持原值，列于 Raw["deferred"]，重启后生效
+// 再次调用会替换之前的监视，Close 时自动停止
+func (d *Dice) WatchConfigFile(path string, interval time.Duration) error {
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		return err
+	}
+	if err := d.ApplyConfig(*cfg); err != nil {
+		return err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if interval <= 0 {
+		interval = defaultConfigWatchInterval
+	}
+
+	stop := make(chan struct{})
+	d.configMu.Lock()
+	if d.configWatchStop != nil {
+		close(d.configWatchStop)
+	}
+	d.configWatchStop = stop
+	d.configMu.Unlock()
+
+	go d.watchConfigFile(path, interval, stat, stop)
+	return nil
+}
+
+const defaultConfigWatchInterval = 3 * time.Second
+
+func (d *Dice) watchConfigFile(path string, interval time.Duration, last os.FileInfo, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			stat, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			if stat.ModTime().Equal(last.ModTime()) && stat.Size() == last.Size() {
+				continue
+			}
+			last = stat
+
+			var deferred []string
+			cfg, err := LoadConfigFile(path)
+			if err == nil {
+				deferred = d.keepRestartOnlyKeys(cfg)
+				err = d.ApplyConfig(*cfg)
+			}
+			d.dispatchConfigReload(path, err, deferred)
+		}
+	}
+}
+
+// keepRestartOnlyKeys 将只能在启动时设置的配置项还原为当前值，返回被忽略的配置项
+// 平台前缀已用于拼接现有的群组、用户ID与人物卡数据，运行中修改会使其无法对应
+func (d *Dice) keepRestartOnlyKeys(cfg *Config) []string {
+	cur := d.currentConfig()
+	var deferred []string
+	if cfg.PlatformPrefix != cur.PlatformPrefix {
+		cfg.PlatformPrefix = cur.PlatformPrefix
+		deferred = append(deferred, "platformPrefix")
+	}
+	return deferred
+}
+
+// stopConfigWatch 停止配置文件监视
+func (d *Dice) stopConfigWatch() {
+	d.configMu.Lock()
+	defer d.configMu.Unlock()
+	if d.configWatchStop != nil {
+		close(d.configWatchStop)
+		d.configWatchStop = nil
+	}
+}
+
+func (d *Dice) dispatchConfigReload(path string, err error, deferred []string) {
+	evt := &types.AdapterEvent{
+		PostType: EventPostTypeInternal,
+		Type:     EventTypeConfigReload,
+		Time:     time.Now().Unix(),
+		Raw:      map[string]any{"path": path},
+	}
+	if err != nil {
+		evt.Raw["error"] = err.Error()
+	} else if len(deferred) > 0 {
+		evt.Raw["deferred"] = deferred
+	}
+	d.runEventHooks("", evt)
+}
+
+// MarshalText 以名称形式写出投递策略
+func (p ReplyRoutePolicy) MarshalText() ([]byte, error) {
+	switch p {
+	case ReplyRouteStrict:
+		return []byte("strict"), nil
+	case ReplyRouteFallback:
+		return []byte("fallback"), nil
+	case ReplyRouteBroadcast:
+		return []byte("broadcast"), nil
+	}
+	return nil, fmt.Errorf("unknown reply route policy %d", int(p))
+}
+
+// UnmarshalText 解析 strict/fallback/broadcast
+func (p *ReplyRoutePolicy) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "strict":
+		*p = ReplyRouteStrict
+	case "fallback":
+		*p = ReplyRouteFallback
+	case "broadcast":
+		*p = ReplyRouteBroadcast
+	default:
+		return fmt.Errorf("unknown reply route policy %q, expect strict/fallback/broadcast", string(text))
+	}
+	return nil
+}
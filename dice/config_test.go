@@ -0,0 +1,140 @@
+package dice
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+func TestParseConfig(t *testing.T) {
+	as := assert.New(t)
+
+	cfg, err := ParseConfig([]byte(`
+commandPrefix: ["!"]
+defaultSystem: dnd5e
+opCountLimit: 5000
+masters: [QQ:1]
+replyRoutePolicy: broadcast
+rateLimit:
+  enabled: true
+  personalBurst: 5
+executeTimeout: 5s
+`))
+	require.NoError(t, err)
+	as.Equal([]string{"!"}, cfg.CommandPrefix)
+	as.Equal("QQ", cfg.PlatformPrefix, "missing keys keep defaults")
+	as.Equal("dnd5e", cfg.DefaultSystem)
+	as.EqualValues(5000, cfg.OpCountLimit)
+	as.Equal(ReplyRouteBroadcast, cfg.ReplyRoutePolicy)
+	as.Equal(5, cfg.RateLimit.PersonalBurst)
+	as.Equal(20, cfg.RateLimit.GroupBurst)
+	as.Equal(5*time.Second, cfg.ExecuteTimeout)
+
+	cfg, err = ParseConfig([]byte(`{"platformPrefix": "KOOK", "masters": ["KOOK:1"]}`))
+	require.NoError(t, err)
+	as.Equal("KOOK", cfg.PlatformPrefix)
+	as.Equal([]string{"KOOK:1"}, cfg.Masters)
+
+	_, err = ParseConfig([]byte("commandPrefx: ['.']"))
+	as.ErrorContains(err, "commandPrefx")
+
+	_, err = ParseConfig([]byte("replyRoutePolicy: nowhere"))
+	as.ErrorContains(err, "nowhere")
+
+	_, err = ParseConfig([]byte("commandPrefix: []\nopCountLimit: -1\nreplyRoutePolicy: fallback"))
+	var cfgErr *ConfigError
+	as.True(errors.As(err, &cfgErr))
+	as.ErrorContains(err, "config commandPrefix")
+	as.ErrorContains(err, "config opCountLimit")
+	as.ErrorContains(err, "config defaultAdapterId")
+}
+
+func TestApplyConfig(t *testing.T) {
+	as := assert.New(t)
+
+	d := newTestDice(t, nil)
+	d.MasterAdd("QQ:manual")
+
+	cfg := DefaultConfig()
+	cfg.DefaultSystem = "unknown"
+	err := d.ApplyConfig(cfg)
+	var cfgErr *ConfigError
+	if as.True(errors.As(err, &cfgErr)) {
+		as.Equal("defaultSystem", cfgErr.Key)
+	}
+
+	cfg = DefaultConfig()
+	cfg.DefaultSystem = "dnd5e"
+	cfg.Masters = []string{"QQ:a", "QQ:b"}
+	as.NoError(d.ApplyConfig(cfg))
+	as.ElementsMatch([]string{"QQ:manual", "QQ:a", "QQ:b"}, d.ListMasters())
+
+	cfg.Masters = []string{"QQ:b"}
+	as.NoError(d.ApplyConfig(cfg))
+	as.ElementsMatch([]string{"QQ:manual", "QQ:b"}, d.ListMasters())
+
+	d.exec(groupMessage("QQ:1", "", "", "hi"))
+	group, ok := d.GroupInfoManager.Load("QQ-Group:1")
+	if as.True(ok) {
+		as.Equal("dnd5e", group.System)
+	}
+}
+
+func TestWatchConfigFile(t *testing.T) {
+	as := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("commandPrefix: ['.']\n"), 0o644))
+
+	d := newTestDice(t, nil)
+	reloads := make(chan *types.AdapterEvent, 4)
+	_, err := d.RegisterEventHook("reload", types.HookPriorityNormal, func(_ types.DiceLike, _ string, evt *types.AdapterEvent) types.HookResult {
+		if evt.Type == EventTypeConfigReload {
+			reloads <- evt
+		}
+		return types.HookResultContinue
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, d.WatchConfigFile(path, 10*time.Millisecond))
+	as.Equal([]string{"."}, d.currentConfig().CommandPrefix)
+
+	waitReload := func() *types.AdapterEvent {
+		select {
+		case evt := <-reloads:
+			return evt
+		case <-time.After(2 * time.Second):
+			t.Fatal("config reload not observed")
+			return nil
+		}
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte("commandPrefix: ['!', '.']\nmasters: [QQ:9]\n"), 0o644))
+	evt := waitReload()
+	as.Nil(evt.Raw["error"])
+	as.Equal([]string{"!", "."}, d.currentConfig().CommandPrefix)
+	as.True(d.IsMaster("QQ:9"))
+
+	// 非法配置不会生效
+	require.NoError(t, os.WriteFile(path, []byte("opCountLimit: 0\n"), 0o644))
+	evt = waitReload()
+	as.Contains(evt.Raw["error"], "opCountLimit")
+	as.Equal([]string{"!", "."}, d.currentConfig().CommandPrefix)
+
+	// 平台前缀需要重启后生效，其余配置项照常更新
+	require.NoError(t, os.WriteFile(path, []byte("commandPrefix: ['!']\nplatformPrefix: KOOK\n"), 0o644))
+	evt = waitReload()
+	as.Nil(evt.Raw["error"])
+	as.Equal([]string{"platformPrefix"}, evt.Raw["deferred"])
+	as.Equal("QQ", d.currentConfig().PlatformPrefix)
+	as.Equal([]string{"!"}, d.currentConfig().CommandPrefix)
+
+	d.stopConfigWatch()
+}
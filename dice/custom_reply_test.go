@@ -0,0 +1,120 @@
+package dice
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sealdice/smallseal/dice/exts"
+)
+
+const testReplyRules = `
+items:
+  - name: 早安
+    match: exact
+    keyword: 早
+    replies:
+      - text: 早上好，{$t玩家}
+  - name: 骰子
+    match: regex
+    keyword: ^来个(\d+)面骰$
+    condition: $t1 != '0'
+    replies:
+      - text: '{$t1}面骰: 已备好'
+  - name: 骰子兜底
+    match: prefix
+    keyword: 来个
+    cooldown: 60
+    replies:
+      - text: 没有这种骰子
+        weight: 3
+      - text: 找不到
+  - name: 停用
+    match: contains
+    keyword: 停用
+    disabled: true
+    replies:
+      - text: 不应出现
+`
+
+func newCustomReplyDice(t *testing.T) (*Dice, string, func(uid string, content string) string) {
+	path := filepath.Join(t.TempDir(), "reply.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testReplyRules), 0o644))
+
+	cfg := DefaultConfig()
+	cfg.CustomReplyFile = path
+	cfg.Masters = []string{"QQ:1"}
+	d := newTestDice(t, &cfg)
+	send := func(uid string, content string) string {
+		return d.send(groupMessage(uid, "阿尔", "", content))
+	}
+	return d.Dice, path, send
+}
+
+func TestCustomReplyMatch(t *testing.T) {
+	as := assert.New(t)
+	_, _, send := newCustomReplyDice(t)
+
+	as.Equal("早上好，<阿尔>", send("QQ:2", "早"))
+	as.Empty(send("QQ:2", "早啊"), "exact match only")
+	as.Equal("6面骰: 已备好", send("QQ:2", "来个6面骰"))
+	as.Empty(send("QQ:2", "停用"))
+
+	// 条件不满足时交给后面的规则，冷却期间不再回复
+	as.Contains([]string{"没有这种骰子", "找不到"}, send("QQ:2", "来个0面骰"))
+	as.Empty(send("QQ:2", "来个0面骰"))
+	as.Equal("6面骰: 已备好", send("QQ:2", "来个6面骰"), "cooldown is per rule")
+
+	as.NotEqual("早上好，<阿尔>", send("QQ:2", ".r 早"), "commands are not matched")
+}
+
+func TestCustomReplyCommand(t *testing.T) {
+	as := assert.New(t)
+	d, path, send := newCustomReplyDice(t)
+
+	as.NotContains(send("QQ:2", ".reply list"), "自定义回复规则")
+
+	as.Contains(send("QQ:1", ".reply list"), "1. [开] 早安 exact:早 回复1条")
+	as.Equal("已添加自定义回复: 晚安", send("QQ:1", ".reply add 晚安 exact 晚安 晚安，{$t玩家}\n好梦"))
+	as.Equal("晚安，<阿尔>\n好梦", send("QQ:2", "晚安"))
+	as.Contains(send("QQ:1", ".reply add 晚安 exact 晚 x"), "规则 晚安 已存在")
+	as.Contains(send("QQ:1", ".reply add 坏 regex ( x"), "正则表达式错误")
+
+	as.Equal("已停用自定义回复: 早安", send("QQ:1", ".reply off 早安"))
+	as.Empty(send("QQ:2", "早"))
+	as.Equal("已删除自定义回复: 晚安", send("QQ:1", ".reply del 晚安"))
+	as.Contains(send("QQ:1", ".reply del 晚安"), "未找到规则 晚安")
+
+	// 修改会写回文件
+	cfg, err := exts.LoadReplyConfig(path)
+	require.NoError(t, err)
+	require.Len(t, cfg.Items, 4)
+	as.True(cfg.Items[0].Disabled)
+
+	group, ok := d.GroupInfoManager.Load("QQ-Group:1")
+	require.True(t, ok)
+	as.NotNil(group.LastCustomReplyTime)
+
+	require.NoError(t, os.WriteFile(path, []byte(testReplyRules), 0o644))
+	as.Equal("已重新读取自定义回复，共4条规则", send("QQ:1", ".reply reload"))
+	as.Equal("早上好，<阿尔>", send("QQ:2", "早"))
+}
+
+func TestCustomReplyFileConfigError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reply.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("items:\n  - name: a\n    match: fuzzy\n    keyword: a\n    replies: [{text: b}]\n"), 0o644))
+
+	d := NewDice()
+	cfg := DefaultConfig()
+	cfg.CustomReplyFile = path
+	err := d.ApplyConfig(cfg)
+	var cfgErr *ConfigError
+	require.True(t, errors.As(err, &cfgErr))
+	assert.Equal(t, "customReplyFile", cfgErr.Key)
+	assert.Contains(t, err.Error(), "未知的匹配方式")
+	assert.Empty(t, d.Config.CustomReplyFile, "config is kept on failure")
+}
@@ -1,14 +1,19 @@
 package dice
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/sealdice/smallseal/dice/attrs"
+	"github.com/sealdice/smallseal/dice/ban"
 	"github.com/sealdice/smallseal/dice/exts"
+	"github.com/sealdice/smallseal/dice/gamelog"
+	"github.com/sealdice/smallseal/dice/helpdoc"
 	"github.com/sealdice/smallseal/dice/types"
 	"github.com/sealdice/smallseal/utils"
 )
@@ -17,8 +22,17 @@ type Dice struct {
 	GroupInfoManager GroupInfoManager
 
 	attrsManager *attrs.AttrsManager
+	banManager   *ban.BanManager
+	replyStore   *exts.ReplyStore
+	helpManager  *helpdoc.HelpManager
+	logManager   *gamelog.LogManager
 	gameSystem   utils.SyncMap[string, *types.GameSystemTemplateV2]
 
+	adapterMap utils.SyncMap[string, *adapterEntry]
+
+	// Deprecated: 请使用 RegisterAdapter 或 RegisterAdapterSender 注册适配器。
+	// 为兼容旧代码保留，RegisterAdapterSender 注册的回调会同步写入此处；
+	// 直接写入的回调在没有同名适配器时，按回复的 AdapterId 调用
 	CallbackForSendMsg utils.SyncMap[string, func(msg *types.MsgToReply)]
 
 	inboundHooks  hookRegistry[types.MessageInHook]
@@ -27,11 +41,30 @@ type Dice struct {
 
 	ExtList []*types.ExtInfo
 
+	userRateLimits utils.SyncMap[string, *userRateLimit] // 个人指令限速，见 checkRateLimit
+
 	curCommandID atomic.Int64
+	pipeline     atomic.Pointer[pipeline]
 
-	Config struct {
-		CommandPrefix []string
-	}
+	closeMu   sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+	closeErr  error
+	inflight  sync.WaitGroup
+
+	Config          Config
+	configMu        sync.RWMutex
+	configMasters   []string      // 由配置文件添加的骰主
+	configWatchStop chan struct{} // 配置文件监视的停止信号
+
+	autoQuitMu    sync.Mutex
+	autoQuitStop  chan struct{}           // 自动退群任务的停止信号
+	autoQuitMarks map[string]autoQuitMark // 已发出退群预告的群
+
+	pendingQuitMu     sync.Mutex
+	pendingQuits      map[*pendingQuit]struct{} // 等待执行的延迟退群，见 ScheduleQuitGroup
+	pendingQuitClosed bool
+	pendingQuitWG     sync.WaitGroup
 
 	masterList utils.SyncMap[string, bool]
 }
@@ -39,15 +72,17 @@ type Dice struct {
 func NewDice() *Dice {
 	d := &Dice{
 		attrsManager:     &attrs.AttrsManager{},
+		banManager:       ban.NewBanManager(),
+		replyStore:       exts.NewReplyStore(),
+		helpManager:      helpdoc.NewHelpManager(),
+		logManager:       gamelog.NewLogManager(),
 		GroupInfoManager: NewDefaultGroupInfoManager(),
 
-		CallbackForSendMsg: utils.SyncMap[string, func(msg *types.MsgToReply)]{},
-
 		masterList: utils.SyncMap[string, bool]{},
 	}
 
 	d.attrsManager.Init()
-	d.Config.CommandPrefix = []string{".", "。"}
+	d.Config = DefaultConfig()
 
 	for _, asset := range exts.BuiltinGameSystemTemplateAssets() {
 		gs, err := types.LoadGameSystemTemplateFromData(asset.Data, asset.Filename)
@@ -60,6 +95,8 @@ func NewDice() *Dice {
 	exts.RegisterBuiltinExtCore(d)
 	exts.RegisterBuiltinExtCoc7(d)
 	exts.RegisterBuiltinExtDnd5e(d)
+	exts.RegisterBuiltinExtReply(d, d.replyStore)
+	exts.RegisterBuiltinExtLog(d, d.logManager)
 
 	return d
 }
@@ -68,67 +105,56 @@ func (d *Dice) getNextCommandID() int64 {
 	return d.curCommandID.Add(1)
 }
 
-func (d *Dice) Execute(adapterId string, msg *types.Message) (solved bool) {
+// Execute 处理一条消息，Close 之后不再处理
+func (d *Dice) Execute(adapterId string, msg *types.Message) *ExecuteResult {
+	if !d.beginExecute() {
+		return &ExecuteResult{Err: ErrDiceClosed}
+	}
+	defer d.inflight.Done()
+	return d.execute(adapterId, msg)
+}
+
+func (d *Dice) execute(adapterId string, msg *types.Message) (result *ExecuteResult) {
+	result = &ExecuteResult{}
 	if msg == nil {
 		return
 	}
 
-	mctx := &types.MsgContext{Dice: d, AdapterId: adapterId, TextTemplateMap: DefaultTextMap, FallbackTextTemplate: DefaultTextMap}
-	mctx.AttrsManager = d.attrsManager
+	start := time.Now()
+	collector := &replyCollector{}
+	defer func() {
+		result.Replies = collector.snapshot()
+		result.Duration = time.Since(start)
+	}()
 
 	msg.Message = msg.Segments.ToText()
-	if msg.MessageType != "group" && msg.MessageType != "private" {
+	if !types.IsGroupScene(msg.MessageType) && msg.MessageType != types.MessageScenePrivate {
 		return
 	}
-
-	groupInfo, ok := d.GroupInfoManager.Load(msg.GroupID)
-
-	if !ok {
-		groupInfo = &types.GroupInfo{
-			GroupId: msg.GroupID,
-			System:  "coc7",
-			Active:  true,
+	if types.IsChannelScene(msg.MessageType) && msg.GroupID == "" {
+		if msg.ChannelID == "" {
+			return
 		}
-
-		groupInfo.ExtActiveStates = &utils.SyncMap[string, bool]{}
-		groupInfo.BotList = &utils.SyncMap[string, bool]{}
-		groupInfo.Players = &utils.SyncMap[string, *types.GroupPlayerInfo]{}
-		for _, ext := range d.GetExtList() {
-			if ext.AutoActive {
-				groupInfo.SetExtensionActive(ext.Name, true)
-			}
+		platform := msg.Platform
+		if platform == "" {
+			platform = d.adapterPlatform(adapterId, d.currentConfig())
 		}
-		groupInfo.ActivatedExtList = groupInfo.GetActiveExtensions(d.GetExtList())
-		d.GroupInfoManager.Store(msg.GroupID, groupInfo)
+		msg.GroupID = types.ChannelGroupID(platform, msg.GuildID, msg.ChannelID)
 	}
 
-	if groupInfo.BotList == nil {
-		groupInfo.BotList = &utils.SyncMap[string, bool]{}
-	}
-	if groupInfo.Players == nil {
-		groupInfo.Players = &utils.SyncMap[string, *types.GroupPlayerInfo]{}
+	if d.isBannedMessage(msg) {
+		return
 	}
 
-	mctx.GameSystem, _ = d.gameSystem.Load(groupInfo.System)
-	mctx.Group = groupInfo
-
-	mctx.IsCurGroupBotOn = groupInfo.Active
-
-	player, exists := groupInfo.Players.Load(msg.Sender.UserID)
-	if !exists {
-		player = &types.GroupPlayerInfo{
-			UserId: msg.Sender.UserID,
-			Name:   msg.Sender.Nickname,
+	cfg := d.currentConfig()
+	mctx := d.newMsgContext(adapterId, msg, cfg)
+	mctx.ReplyRecorder = collector.add
+	defer func() {
+		if err := safeCall("afterExecute", mctx.RunAfterExecute); err != nil {
+			d.dispatchExecuteError(adapterId, msg, err)
 		}
-		groupInfo.Players.Store(msg.Sender.UserID, player)
-	} else if msg.Sender.Nickname != "" {
-		if player.Name == "" {
-			player.Name = msg.Sender.Nickname
-		}
-	}
-
-	player.InGroup = msg.MessageType == "group"
-	mctx.Player = player
+	}()
+	groupInfo := mctx.Group
 
 	groupInfo.UpdatedAtTime = time.Now().Unix()
 
@@ -136,6 +162,7 @@ func (d *Dice) Execute(adapterId string, msg *types.Message) (solved bool) {
 	groupInfo.ActivatedExtList = activeExtensions
 
 	if d.runMessageInHooks(adapterId, msg, mctx) {
+		result.HookAborted = true
 		return
 	}
 
@@ -147,11 +174,22 @@ func (d *Dice) Execute(adapterId string, msg *types.Message) (solved bool) {
 	}
 	sort.Sort(sort.Reverse(sort.StringSlice(cmdLst)))
 
-	platformPrefix := "QQ"
-	cmdArgs := types.CommandParse(msg.Message, cmdLst, d.Config.CommandPrefix, platformPrefix, false)
+	cmdText, atInfo := types.AtParse(msg.Segments, mctx.Platform)
+	cmdArgs := types.CommandParse(cmdText, cmdLst, cfg.CommandPrefix, mctx.Platform, true)
 
 	if cmdArgs != nil {
+		cmdArgs.At = atInfo
+		cmdArgs.SetupAtInfo(msg.SelfID)
+		// N# 仅对开启了 EnableExecuteTimesParse 的指令生效，以同名指令的归属扩展为准
+		if cmd := findCommand(activeExtensions, cmdArgs.Command); cmd == nil || !cmd.EnableExecuteTimesParse {
+			cmdArgs.RevokeExecuteTimesParse()
+		}
 		mctx.CommandId = d.getNextCommandID()
+		result.CommandId = mctx.CommandId
+		result.CmdArgs = cmdArgs
+		if !d.checkRateLimit(mctx, msg) {
+			return
+		}
 	}
 
 	sendHelp := func(cmd *types.CmdItemInfo) {
@@ -173,20 +211,14 @@ func (d *Dice) Execute(adapterId string, msg *types.Message) (solved bool) {
 		exts.ReplyToSender(mctx, msg, helpText)
 	}
 
-	allowWhenInactive := func(cmd *types.CmdItemInfo) bool {
-		if cmd == nil {
-			return false
-		}
-		switch strings.ToLower(cmd.Name) {
-		case "bot", "dismiss":
-			return true
-		default:
-			return false
-		}
-	}
+	groupActive := mctx.Group == nil || mctx.IsCurGroupBotOn
 
-	groupActive := mctx.Group == nil || mctx.Group.Active
+	if cmdArgs != nil && (groupActive || cmdArgs.AmIBeMentionedFirst) {
+		d.runCommandOverrides(adapterId, mctx, msg, cmdArgs, activeExtensions, result)
+	}
 
+	// 同名指令只交给优先级最高的扩展，其标志不允许执行时也不再交给后面的扩展
+	cmdOwnerFound := false
 	for _, _i := range activeExtensions {
 		i := _i
 		if mctx.Group != nil && !mctx.Group.IsExtensionActive(i.Name) {
@@ -194,21 +226,52 @@ func (d *Dice) Execute(adapterId string, msg *types.Message) (solved bool) {
 		}
 
 		if groupActive && i.OnNotCommandReceived != nil {
-			i.OnNotCommandReceived(mctx, msg)
+			if err := safeCall("ext:"+i.Name+":OnNotCommandReceived", func() {
+				i.OnNotCommandReceived(mctx, msg)
+			}); err != nil {
+				d.dispatchExecuteError(adapterId, msg, err)
+			}
 		}
 		if groupActive && i.OnCommandReceived != nil && cmdArgs != nil {
-			i.OnCommandReceived(mctx, msg, cmdArgs)
+			if err := safeCall("ext:"+i.Name+":OnCommandReceived", func() {
+				i.OnCommandReceived(mctx, msg, cmdArgs)
+			}); err != nil {
+				d.replyExecuteError(adapterId, mctx, msg, err)
+				result.Err = err
+			}
 		}
 
-		if cmdArgs != nil && !solved {
+		if cmdArgs != nil && !result.Solved && !cmdOwnerFound {
 			if cmd, ok := i.CmdMap[cmdArgs.Command]; ok {
-				if !groupActive && !allowWhenInactive(cmd) {
+				cmdOwnerFound = true
+				if !cmdFlagsAllow(mctx, cmdArgs, cmd) {
 					continue
 				}
-				result := cmd.Solve(mctx, msg, cmdArgs)
-				if result.Solved {
-					solved = true
-					if result.ShowHelp {
+				result.Command = cmd.Name
+				result.ExtName = i.Name
+				if cmd.DisabledInPrivate && mctx.IsPrivate {
+					exts.ReplyToSender(mctx, msg, exts.DiceFormatTmpl(mctx, "核心:提示_私聊不可用"))
+					result.Solved = true
+					continue
+				}
+				if mctx.PrivilegeLevel < cmd.RequiredPrivilege {
+					exts.ReplyToSender(mctx, msg, exts.PrivilegeDeniedText(mctx, cmd.RequiredPrivilege))
+					result.Solved = true
+					continue
+				}
+				if !mctx.IsPrivate {
+					mctx.Group.RecentDiceSendTime = time.Now().Unix()
+				}
+				ret, err := d.solveGuarded(mctx, msg, cmdArgs, cmd)
+				if err != nil {
+					d.replyExecuteError(adapterId, mctx, msg, err)
+					result.Solved = true
+					result.Err = err
+					continue
+				}
+				if ret.Solved {
+					result.Solved = true
+					if ret.ShowHelp {
 						sendHelp(cmd)
 					}
 				}
@@ -216,7 +279,153 @@ func (d *Dice) Execute(adapterId string, msg *types.Message) (solved bool) {
 		}
 	}
 
-	return solved
+	return result
+}
+
+// findCommand 按扩展顺序查找指令，即实际会处理该指令的扩展所注册的指令
+func findCommand(activeExtensions []*types.ExtInfo, name string) *types.CmdItemInfo {
+	for _, ext := range activeExtensions {
+		if cmd, ok := ext.CmdMap[name]; ok {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// runCommandOverrides 按扩展优先级依次调用 OnCommandOverride，返回 true 的扩展接管该指令，
+// 之后不再进行正常的指令分发。指令权限由接管的扩展自行判断。
+func (d *Dice) runCommandOverrides(adapterId string, mctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs, activeExtensions []*types.ExtInfo, result *ExecuteResult) {
+	for _, _i := range activeExtensions {
+		i := _i
+		if i.OnCommandOverride == nil {
+			continue
+		}
+		if mctx.Group != nil && !mctx.Group.IsExtensionActive(i.Name) {
+			continue
+		}
+		handled := false
+		err := safeCall("ext:"+i.Name+":OnCommandOverride", func() {
+			handled = i.OnCommandOverride(mctx, msg, cmdArgs)
+		})
+		if err != nil {
+			d.replyExecuteError(adapterId, mctx, msg, err)
+			result.Err = err
+			handled = true
+		}
+		if !handled {
+			continue
+		}
+		result.Solved = true
+		result.Command = cmdArgs.Command
+		result.ExtName = i.Name
+		if !mctx.IsPrivate {
+			mctx.Group.RecentDiceSendTime = time.Now().Unix()
+		}
+		return
+	}
+}
+
+// baseMsgContext 构造尚未关联群组与用户的 MsgContext，填入骰子的各个管理器与配置项
+func (d *Dice) baseMsgContext(adapterId string, cfg Config) *types.MsgContext {
+	mctx := &types.MsgContext{Dice: d, AdapterId: adapterId, TextTemplateMap: DefaultTextMap, FallbackTextTemplate: DefaultTextMap}
+	mctx.OpCountLimit = cfg.OpCountLimit
+	mctx.MaxExecuteTimes = cfg.MaxExecuteTimes
+	mctx.ExtConflictReject = cfg.ExtConflictReject
+	mctx.LogExportDir = cfg.LogExportDir
+	mctx.AttrsManager = d.attrsManager
+	mctx.BanManager = d.banManager
+	mctx.HelpManager = d.helpManager
+	mctx.Platform = d.adapterPlatform(adapterId, cfg)
+	return mctx
+}
+
+// newMsgContext 为消息构建上下文，群组信息不存在时按默认设置创建
+func (d *Dice) newMsgContext(adapterId string, msg *types.Message, cfg Config) *types.MsgContext {
+	mctx := d.baseMsgContext(adapterId, cfg)
+
+	groupInfo, ok := d.GroupInfoManager.Load(msg.GroupID)
+
+	if !ok {
+		groupInfo = d.newGroupInfo(msg.GroupID, cfg)
+		d.GroupInfoManager.Store(msg.GroupID, groupInfo)
+	}
+
+	if groupInfo.BotList == nil {
+		groupInfo.BotList = &utils.SyncMap[string, bool]{}
+	}
+	if groupInfo.Players == nil {
+		groupInfo.Players = &utils.SyncMap[string, *types.GroupPlayerInfo]{}
+	}
+	if groupInfo.LastCustomReplyTime == nil {
+		groupInfo.LastCustomReplyTime = &utils.SyncMap[string, float64]{}
+	}
+
+	mctx.DiceID = types.DiceAccountID(adapterId, msg.SelfID)
+	mctx.MessageScene = msg.MessageType
+	if types.IsChannelScene(msg.MessageType) {
+		groupInfo.GuildID = msg.GuildID
+		groupInfo.ChannelID = msg.ChannelID
+	}
+	if types.IsGroupScene(msg.MessageType) {
+		if adapterId != "" && groupInfo.AdapterId != adapterId {
+			groupInfo.AdapterId = adapterId
+		}
+		if groupInfo.DiceIDExistsMap == nil {
+			groupInfo.DiceIDExistsMap = &utils.SyncMap[string, bool]{}
+		}
+		if mctx.DiceID != "" {
+			groupInfo.DiceIDExistsMap.Store(mctx.DiceID, true)
+		}
+	}
+
+	mctx.GameSystem, _ = d.gameSystem.Load(groupInfo.System)
+	mctx.Group = groupInfo
+
+	mctx.IsCurGroupBotOn = groupInfo.IsDiceActive(mctx.DiceID)
+
+	player, exists := groupInfo.Players.Load(msg.Sender.UserID)
+	if !exists {
+		player = &types.GroupPlayerInfo{
+			UserId: msg.Sender.UserID,
+			Name:   msg.Sender.Nickname,
+		}
+		groupInfo.Players.Store(msg.Sender.UserID, player)
+	} else if msg.Sender.Nickname != "" {
+		if player.Name == "" {
+			player.Name = msg.Sender.Nickname
+		}
+	}
+
+	player.InGroup = types.IsGroupScene(msg.MessageType)
+	mctx.Player = player
+	mctx.IsPrivate = msg.MessageType == types.MessageScenePrivate
+	mctx.PrivilegeLevel = d.calcPrivilegeLevel(mctx, msg)
+
+	return mctx
+}
+
+// newGroupInfo 按默认设置创建群组信息，开启自动激活的扩展
+func (d *Dice) newGroupInfo(groupID string, cfg Config) *types.GroupInfo {
+	groupInfo := &types.GroupInfo{
+		GroupId: groupID,
+		System:  cfg.DefaultSystem,
+		Active:  true,
+	}
+
+	groupInfo.ExtActiveStates = &utils.SyncMap[string, bool]{}
+	groupInfo.BotList = &utils.SyncMap[string, bool]{}
+	groupInfo.Players = &utils.SyncMap[string, *types.GroupPlayerInfo]{}
+	groupInfo.LastCustomReplyTime = &utils.SyncMap[string, float64]{}
+	// 默认规则关联的扩展优先开启，与其互斥的自动开启扩展会被跳过
+	if tmpl, ok := d.gameSystem.Load(cfg.DefaultSystem); ok {
+		for _, name := range tmpl.Commands.Set.RelatedExt {
+			if ext := d.ExtFind(name, false); ext != nil {
+				groupInfo.ExtActiveExclusive(ext, d.GetExtList())
+			}
+		}
+	}
+	groupInfo.ActivatedExtList = groupInfo.GetActiveExtensions(d.GetExtList())
+	return groupInfo
 }
 
 func (d *Dice) MasterAdd(uid string) {
@@ -251,19 +460,21 @@ func (d *Dice) IsMaster(uid string) bool {
 	return exists
 }
 
-func (d *Dice) SendReply(msg *types.MsgToReply) {
+// SendReply 经过发送钩子后，将回复投递到 msg.AdapterId 对应的适配器
+func (d *Dice) SendReply(msg *types.MsgToReply) error {
 	if msg == nil {
-		return
+		return nil
 	}
 
 	if d.runMessageOutHooks(msg.AdapterId, msg) {
-		return
+		return nil
 	}
 
-	d.CallbackForSendMsg.Range(func(key string, value func(msg *types.MsgToReply)) bool {
-		value(msg)
-		return true
-	})
+	if err := d.deliverReply(msg); err != nil {
+		d.dispatchSendFailed(msg, err)
+		return err
+	}
+	return nil
 }
 
 func (d *Dice) PersistGroupInfo(groupID string, info *types.GroupInfo) {
@@ -280,6 +491,35 @@ func (d *Dice) AttrsSetIO(io attrs.AttrsIO) {
 	d.attrsManager.SetIO(io)
 }
 
+// BanSetIO 设置黑名单存储，并从中载入已有记录
+func (d *Dice) BanSetIO(io ban.BanIO) error {
+	return d.banManager.SetIO(io)
+}
+
+// BanManager 返回黑名单管理器
+func (d *Dice) BanManager() *ban.BanManager {
+	return d.banManager
+}
+
+// LogSetStore 设置跑团日志存储，为 nil 时恢复为内存存储
+func (d *Dice) LogSetStore(store gamelog.LogStore) {
+	d.logManager.SetStore(store)
+}
+
+// LogStats 统计群内指定日志中各角色的检定情况
+func (d *Dice) LogStats(groupID string, name string) (*gamelog.SessionStats, error) {
+	items, err := d.logManager.Store().Items(groupID, name)
+	if err != nil {
+		return nil, err
+	}
+	return gamelog.Stat(name, items), nil
+}
+
+// HelpManager 返回帮助文档管理器
+func (d *Dice) HelpManager() *helpdoc.HelpManager {
+	return d.helpManager
+}
+
 // SaveAll 手动保存所有未保存的属性数据
 func (d *Dice) SaveAll() error {
 	if d.attrsManager == nil {
@@ -323,12 +563,22 @@ func (d *Dice) UnregisterEventHook(handle types.HookHandle) bool {
 	return d.eventHooks.unregister(handle)
 }
 
+// DispatchEvent 分发适配器事件，先交给 EventHook，未被中止时再调用扩展的生命周期回调
+// 管线运行时与事件所在群的消息在同一队列中处理，避免和指令同时修改群组数据；
+// 管线正在关闭时事件被丢弃，计入 PipelineStats.Rejected
 func (d *Dice) DispatchEvent(adapterID string, evt *types.AdapterEvent) {
-	if evt == nil {
+	if evt == nil || !d.beginExecute() {
 		return
 	}
+	defer d.inflight.Done()
 
-	d.runEventHooks(adapterID, evt)
+	_ = d.runInShard(eventToMessage(evt), func() {
+		if d.runEventHooks(adapterID, evt) {
+			return
+		}
+		d.handleRequestEvent(adapterID, evt)
+		d.dispatchExtensionEvent(adapterID, evt)
+	})
 }
 
 func (d *Dice) runMessageInHooks(adapterID string, msg *types.Message, mctx *types.MsgContext) bool {
@@ -338,7 +588,14 @@ func (d *Dice) runMessageInHooks(adapterID string, msg *types.Message, mctx *typ
 	}
 
 	for _, entry := range entries {
-		switch entry.handler(d, adapterID, msg, mctx) {
+		var result types.HookResult
+		if err := d.guardedCtxCall(mctx, "hook:in:"+entry.name, func() {
+			result = entry.handler(d, adapterID, msg, mctx)
+		}); err != nil {
+			d.dispatchExecuteError(adapterID, msg, err)
+			continue
+		}
+		switch result {
 		case types.HookResultContinue:
 			continue
 		case types.HookResultStop:
@@ -360,7 +617,14 @@ func (d *Dice) runMessageOutHooks(adapterID string, reply *types.MsgToReply) boo
 	}
 
 	for _, entry := range entries {
-		switch entry.handler(d, adapterID, reply) {
+		var result types.HookResult
+		if err := d.guardedCall(context.Background(), "hook:out:"+entry.name, func(context.Context) {
+			result = entry.handler(d, adapterID, reply)
+		}); err != nil {
+			d.dispatchExecuteError(adapterID, nil, err)
+			continue
+		}
+		switch result {
 		case types.HookResultContinue:
 			continue
 		case types.HookResultStop:
@@ -375,22 +639,34 @@ func (d *Dice) runMessageOutHooks(adapterID string, reply *types.MsgToReply) boo
 	return false
 }
 
-func (d *Dice) runEventHooks(adapterID string, evt *types.AdapterEvent) {
+func (d *Dice) runEventHooks(adapterID string, evt *types.AdapterEvent) bool {
 	entries := d.eventHooks.snapshot()
 	if len(entries) == 0 {
-		return
+		return false
 	}
 
 	for _, entry := range entries {
-		switch entry.handler(d, adapterID, evt) {
+		var result types.HookResult
+		if err := d.guardedCall(context.Background(), "hook:event:"+entry.name, func(context.Context) {
+			result = entry.handler(d, adapterID, evt)
+		}); err != nil {
+			// 处理内部错误事件时出错不再上报，避免循环
+			if evt.PostType != EventPostTypeInternal {
+				d.dispatchExecuteError(adapterID, nil, err)
+			}
+			continue
+		}
+		switch result {
 		case types.HookResultContinue:
 			continue
 		case types.HookResultStop:
-			return
+			return false
 		case types.HookResultAbort:
-			return
+			return true
 		default:
 			continue
 		}
 	}
+
+	return false
 }
@@ -45,31 +45,19 @@ func TestBotOffDisablesGroupCommands(t *testing.T) {
 		t.Fatalf("chdir: %v", err)
 	}
 
-	d := NewDice()
+	d := newTestDice(t, nil)
 	tracker := newTrackingGroupInfoManager()
 	d.GroupInfoManager = tracker
 
-	var replies []string
-	d.CallbackForSendMsg.Store("test", func(msg *types.MsgToReply) {
-		replies = append(replies, msg.Segments.ToText())
-	})
-
-	send := func(content string) {
-		msg := &types.Message{
-			MessageType: "group",
-			GroupID:     "QQ-Group:12345",
-			Sender: types.SenderBase{
-				UserID:   "user",
-				Nickname: "tester",
-			},
-			Platform: "test",
-			Segments: types.MessageSegments{&types.TextElement{Content: content}},
-		}
-		d.Execute("test", msg)
+	send := func(content string) []string {
+		msg := groupMessage("user", "tester", "admin", content)
+		msg.GroupID = "QQ-Group:12345"
+		msg.Platform = "test"
+		d.exec(msg)
+		return d.texts()
 	}
 
-	send(".roll 1d20")
-	if len(replies) == 0 {
+	if len(send(".roll 1d20")) == 0 {
 		t.Fatalf("expected roll to reply before bot off")
 	}
 	if tracker.storeCalls != 1 {
@@ -95,9 +83,7 @@ func TestBotOffDisablesGroupCommands(t *testing.T) {
 		t.Fatalf("expected persisted group state to be inactive")
 	}
 
-	before := len(replies)
-	send(".roll 1d20")
-	if len(replies) != before {
+	if len(send(".roll 1d20")) != 0 {
 		t.Fatalf("roll should not reply when bot is off")
 	}
 	if tracker.storeCalls != 2 {
@@ -0,0 +1,84 @@
+package dice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sealdice/smallseal/dice/types"
+	"github.com/sealdice/smallseal/utils"
+)
+
+func TestBotOffPerAccount(t *testing.T) {
+	as := assert.New(t)
+
+	d := NewDice()
+	replies := map[string]int{}
+	for _, id := range []string{"qq1", "qq2"} {
+		adapterID := id
+		require.NoError(t, d.RegisterAdapterSender(adapterID, func(*types.MsgToReply) {
+			replies[adapterID]++
+		}))
+	}
+
+	send := func(adapterID string, selfID string, content string) {
+		d.Execute(adapterID, &types.Message{
+			MessageType: "group",
+			GroupID:     "QQ-Group:1",
+			SelfID:      selfID,
+			Sender:      types.SenderBase{UserID: "QQ:10", Nickname: "tester", GroupRole: "admin"},
+			Segments:    types.MessageSegments{&types.TextElement{Content: content}},
+		})
+	}
+
+	send("qq1", "QQ:1", ".bot off")
+	as.Equal(1, replies["qq1"])
+
+	send("qq1", "QQ:1", ".r d20")
+	send("qq2", "QQ:2", ".r d20")
+	as.Equal(map[string]int{"qq1": 1, "qq2": 1}, replies, "only the account that received .bot off is silenced")
+
+	group, ok := d.GroupInfoManager.Load("QQ-Group:1")
+	require.True(t, ok)
+	as.True(group.DiceIDExistsMap.Exists("qq1:QQ:1"))
+	as.True(group.DiceIDExistsMap.Exists("qq2:QQ:2"))
+	as.False(group.IsDiceActive("qq1:QQ:1"))
+	as.True(group.IsDiceActive("qq2:QQ:2"))
+
+	send("qq2", "QQ:2", ".bot off")
+	as.False(group.Active, "Active turns off once every account is off")
+
+	send("qq1", "QQ:1", ".bot on")
+	send("qq1", "QQ:1", ".r d20")
+	as.Equal(3, replies["qq1"])
+	as.True(group.Active)
+}
+
+func TestIsDiceActiveFallsBackToActive(t *testing.T) {
+	as := assert.New(t)
+
+	group := &types.GroupInfo{Active: false, DiceIDExistsMap: &utils.SyncMap[string, bool]{}}
+	group.DiceIDExistsMap.Store("qq:QQ:2", true)
+	as.False(group.IsDiceActive("qq:QQ:1"), "legacy groups without per-account state follow Active")
+
+	group.SetDiceActive("qq:QQ:1", true)
+	as.True(group.Active)
+	as.True(group.IsDiceActive("qq:QQ:1"))
+	as.False(group.IsDiceActive("qq:QQ:2"), "other accounts keep the previous Active")
+	as.False(group.IsDiceActive("qq:QQ:3"), "unseen accounts keep the previous Active")
+
+	group = &types.GroupInfo{Active: true}
+	group.SetDiceActive("qq:QQ:1", false)
+	as.False(group.IsDiceActive("qq:QQ:1"))
+	as.True(group.IsDiceActive("qq:QQ:2"))
+
+	// 账号退群后 Active 随剩余的账号更新，全部退出后为关闭
+	group.SetDiceActive("qq:QQ:2", true)
+	as.True(group.Active)
+	group.RemoveDice("qq:QQ:2")
+	as.False(group.Active)
+	group.RemoveDice("qq:QQ:1")
+	as.False(group.Active)
+	as.False(group.IsDiceActive("qq:QQ:2"))
+}
@@ -0,0 +1,128 @@
+package dice
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+type extEventCallback func(ext *types.ExtInfo) func(ctx *types.MsgContext, msg *types.Message)
+
+// dispatchExtensionEvent 将适配器事件转换为消息，调用当前群内已开启扩展的对应回调
+func (d *Dice) dispatchExtensionEvent(adapterID string, evt *types.AdapterEvent) {
+	if evt.PostType == EventPostTypeInternal {
+		return
+	}
+
+	var name string
+	var pick extEventCallback
+	// 骰子入群、成为好友时尚未有机会开关骰子，不检查群内开启状态
+	alwaysOn := false
+
+	switch evt.Type {
+	case types.AdapterEventGroupIncrease:
+		if d.isSelfEvent(evt) {
+			name, alwaysOn = "OnGroupJoined", true
+			pick = func(ext *types.ExtInfo) func(*types.MsgContext, *types.Message) { return ext.OnGroupJoined }
+		} else {
+			name = "OnGroupMemberJoined"
+			pick = func(ext *types.ExtInfo) func(*types.MsgContext, *types.Message) { return ext.OnGroupMemberJoined }
+		}
+	case types.AdapterEventFriendAdd:
+		name, alwaysOn = "OnBecomeFriend", true
+		pick = func(ext *types.ExtInfo) func(*types.MsgContext, *types.Message) { return ext.OnBecomeFriend }
+	case types.AdapterEventGroupRecall, types.AdapterEventFriendRecall:
+		name = "OnMessageDeleted"
+		pick = func(ext *types.ExtInfo) func(*types.MsgContext, *types.Message) { return ext.OnMessageDeleted }
+	case types.AdapterEventMessageEdit:
+		name = "OnMessageEdit"
+		pick = func(ext *types.ExtInfo) func(*types.MsgContext, *types.Message) { return ext.OnMessageEdit }
+	case types.AdapterEventGuildJoined:
+		name, alwaysOn = "OnGuildJoined", true
+		pick = func(ext *types.ExtInfo) func(*types.MsgContext, *types.Message) { return ext.OnGuildJoined }
+	default:
+		return
+	}
+
+	msg := eventToMessage(evt)
+	if d.isBannedMessage(msg) {
+		return
+	}
+
+	mctx := d.newMsgContext(adapterID, msg, d.currentConfig())
+	if !alwaysOn && !mctx.IsCurGroupBotOn {
+		return
+	}
+
+	for _, ext := range mctx.Group.GetActiveExtensions(d.GetExtList()) {
+		cb := pick(ext)
+		if cb == nil {
+			continue
+		}
+		if err := safeCall("ext:"+ext.Name+":"+name, func() {
+			cb(mctx, msg)
+		}); err != nil {
+			d.dispatchExecuteError(adapterID, msg, err)
+		}
+	}
+}
+
+// eventToMessage 由事件构造消息，发送者为事件的当事人
+func eventToMessage(evt *types.AdapterEvent) *types.Message {
+	msg := &types.Message{
+		Time:        evt.Time,
+		MessageType: "private",
+		GroupID:     evt.GroupID,
+		GuildID:     evt.GuildID,
+		ChannelID:   evt.ChannelID,
+		Platform:    evt.Platform,
+		Sender:      types.SenderBase{UserID: evt.UserID},
+	}
+	switch {
+	case evt.GroupID != "":
+		msg.MessageType = "group"
+	case evt.ChannelID != "":
+		msg.MessageType = types.MessageSceneGuild
+		msg.GroupID = types.ChannelGroupID(evt.Platform, evt.GuildID, evt.ChannelID)
+	}
+	if evt.Raw != nil {
+		msg.RawID = evt.Raw["message_id"]
+		if selfID := rawIDString(evt.Raw["self_id"]); selfID != "" {
+			if !strings.Contains(selfID, ":") && evt.Platform != "" {
+				selfID = evt.Platform + ":" + selfID
+			}
+			msg.SelfID = selfID
+		}
+	}
+	return msg
+}
+
+// isSelfEvent 事件当事人是否为骰子自身，依据原始数据中的 self_id 判断
+func (d *Dice) isSelfEvent(evt *types.AdapterEvent) bool {
+	if evt.Raw == nil || evt.UserID == "" {
+		return false
+	}
+	selfID := rawIDString(evt.Raw["self_id"])
+	if selfID == "" {
+		return false
+	}
+	return evt.UserID == selfID || evt.UserID == evt.Platform+":"+selfID
+}
+
+func rawIDString(v any) string {
+	switch id := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return id
+	case float64:
+		return strconv.FormatFloat(id, 'f', -1, 64)
+	case json.Number:
+		return id.String()
+	default:
+		return fmt.Sprint(id)
+	}
+}
@@ -0,0 +1,96 @@
+package dice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+func TestDispatchEventCallsExtensionCallbacks(t *testing.T) {
+	as := assert.New(t)
+
+	d := NewDice()
+	var calls []string
+	record := func(name string) func(*types.MsgContext, *types.Message) {
+		return func(ctx *types.MsgContext, msg *types.Message) {
+			as.NotNil(ctx.Group)
+			as.NotNil(ctx.Player)
+			calls = append(calls, name+":"+msg.Sender.UserID)
+		}
+	}
+	d.RegisterExtension(&types.ExtInfo{
+		Name:                "lifecycle",
+		AutoActive:          true,
+		OnGroupJoined:       record("joined"),
+		OnGroupMemberJoined: record("member"),
+		OnBecomeFriend:      record("friend"),
+		OnMessageDeleted:    record("deleted"),
+		OnMessageEdit:       record("edit"),
+		OnGuildJoined:       record("guild"),
+	})
+
+	dispatch := func(evtType string, groupID string, userID string) {
+		d.DispatchEvent("test", &types.AdapterEvent{
+			Platform: "QQ",
+			PostType: "notice",
+			Type:     evtType,
+			GroupID:  groupID,
+			UserID:   userID,
+			Raw:      map[string]any{"self_id": float64(10000), "message_id": float64(42)},
+		})
+	}
+
+	dispatch(types.AdapterEventGroupIncrease, "QQ-Group:1", "QQ:10000")
+	dispatch(types.AdapterEventGroupIncrease, "QQ-Group:1", "QQ:2")
+	dispatch(types.AdapterEventFriendAdd, "", "QQ:3")
+	dispatch(types.AdapterEventGroupRecall, "QQ-Group:1", "QQ:2")
+	dispatch(types.AdapterEventMessageEdit, "QQ-Group:1", "QQ:2")
+	dispatch(types.AdapterEventGuildJoined, "QQ-Group:2", "QQ:10000")
+	dispatch("group_ban", "QQ-Group:1", "QQ:2")
+	as.Equal([]string{"joined:QQ:10000", "member:QQ:2", "friend:QQ:3", "deleted:QQ:2", "edit:QQ:2", "guild:QQ:10000"}, calls)
+
+	// 骰子关闭时只响应入群和好友事件
+	group, _ := d.GroupInfoManager.Load("QQ-Group:1")
+	group.Active = false
+	calls = nil
+	dispatch(types.AdapterEventGroupIncrease, "QQ-Group:1", "QQ:4")
+	dispatch(types.AdapterEventGroupIncrease, "QQ-Group:1", "QQ:10000")
+	as.Equal([]string{"joined:QQ:10000"}, calls)
+
+	// 钩子中止后不再调用扩展
+	calls = nil
+	_, err := d.RegisterEventHook("abort", types.HookPriorityNormal, func(types.DiceLike, string, *types.AdapterEvent) types.HookResult {
+		return types.HookResultAbort
+	})
+	as.NoError(err)
+	dispatch(types.AdapterEventFriendAdd, "", "QQ:3")
+	as.Empty(calls)
+}
+
+func TestDispatchEventCallbackPanicIsIsolated(t *testing.T) {
+	as := assert.New(t)
+
+	d := NewDice()
+	var errs []*types.AdapterEvent
+	_, err := d.RegisterEventHook("collect", types.HookPriorityNormal, func(_ types.DiceLike, _ string, evt *types.AdapterEvent) types.HookResult {
+		if evt.PostType == EventPostTypeInternal {
+			errs = append(errs, evt)
+		}
+		return types.HookResultContinue
+	})
+	as.NoError(err)
+	d.RegisterExtension(&types.ExtInfo{
+		Name:           "bad",
+		AutoActive:     true,
+		OnBecomeFriend: func(*types.MsgContext, *types.Message) { panic("oops") },
+	})
+
+	as.NotPanics(func() {
+		d.DispatchEvent("test", &types.AdapterEvent{Platform: "QQ", Type: types.AdapterEventFriendAdd, UserID: "QQ:1"})
+	})
+	if as.Len(errs, 1) {
+		as.Equal("ext:bad:OnBecomeFriend", errs[0].SubType)
+	}
+}
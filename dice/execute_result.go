@@ -0,0 +1,45 @@
+package dice
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+// ExecuteResult 一次 Execute 的执行结果，供嵌入方记录日志或测试使用
+type ExecuteResult struct {
+	Solved      bool           // 是否有指令处理了该消息
+	CommandId   int64          // 指令序号，非指令消息为0
+	Command     string         // 匹配到的指令名(CmdItemInfo.Name)
+	ExtName     string         // 指令所属扩展
+	CmdArgs     *types.CmdArgs // 指令解析结果，非指令消息为nil
+	HookAborted bool           // 是否被 MessageInHook 中止
+
+	Replies  []*types.MsgToReply // 本次执行产生的回复
+	Duration time.Duration       // 执行耗时
+	Err      error               // 执行中发生的错误，如 panic 或超时
+}
+
+// IsCommand 消息是否被解析为指令
+func (r *ExecuteResult) IsCommand() bool {
+	return r.CmdArgs != nil
+}
+
+// replyCollector 汇总执行期间的回复，超时的指令可能在另一个 goroutine 中继续回复
+type replyCollector struct {
+	mu      sync.Mutex
+	replies []*types.MsgToReply
+}
+
+func (c *replyCollector) add(reply *types.MsgToReply) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.replies = append(c.replies, reply)
+}
+
+func (c *replyCollector) snapshot() []*types.MsgToReply {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*types.MsgToReply(nil), c.replies...)
+}
@@ -0,0 +1,46 @@
+package dice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+func TestExecuteResult(t *testing.T) {
+	as := assert.New(t)
+
+	d := newTestDice(t, nil)
+	send := func(content string) *ExecuteResult {
+		return d.exec(groupMessage("QQ:1", "tester", "", content))
+	}
+
+	result := send(".r d20")
+	as.True(result.Solved)
+	as.True(result.IsCommand())
+	as.NotZero(result.CommandId)
+	as.Equal("roll", result.Command)
+	as.Equal("core", result.ExtName)
+	as.Equal("r", result.CmdArgs.Command)
+	as.NoError(result.Err)
+	if as.Len(result.Replies, 1) {
+		as.Equal(result.CommandId, result.Replies[0].CommandId)
+	}
+
+	result = send("hello")
+	as.False(result.Solved)
+	as.False(result.IsCommand())
+	as.Empty(result.Replies)
+
+	_, err := d.RegisterMessageInHook("abort", types.HookPriorityNormal, func(types.DiceLike, string, *types.Message, *types.MsgContext) types.HookResult {
+		return types.HookResultAbort
+	})
+	as.NoError(err)
+	result = send(".r d20")
+	as.True(result.HookAborted)
+	as.False(result.Solved)
+	as.Nil(result.CmdArgs)
+
+	as.NotNil(d.Execute("test", nil))
+}
@@ -0,0 +1,62 @@
+package dice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sealdice/smallseal/dice/exts"
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+func TestExecuteTimesParse(t *testing.T) {
+	as := assert.New(t)
+	d := newTestDice(t, nil)
+	d.RegisterExtension(&types.ExtInfo{
+		Name:       "echo",
+		AutoActive: true,
+		CmdMap: types.CmdMapCls{
+			"echo": &types.CmdItemInfo{
+				Name: "echo",
+				Solve: func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs) types.CmdExecuteResult {
+					exts.ReplyToSender(ctx, msg, cmdArgs.RawArgs)
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				},
+			},
+		},
+	})
+
+	send := func(content string) (*ExecuteResult, string) {
+		result := d.exec(groupMessage("QQ:1", "user", "", content))
+		return result, d.reply()
+	}
+
+	result, reply := send(".3#r d1")
+	as.Equal(3, result.CmdArgs.SpecialExecuteTimes)
+	as.Contains(reply, "掷骰3次")
+
+	result, reply = send(".r 2#d1")
+	as.Equal(2, result.CmdArgs.SpecialExecuteTimes)
+	as.Contains(reply, "掷骰2次")
+
+	_, reply = send(".2#ra 50")
+	as.Contains(reply, "进行了2次检定")
+
+	_, reply = send(".13#r d1")
+	as.NotContains(reply, "掷骰13次", "exceeds maxExecuteTimes")
+	as.NotEmpty(reply)
+
+	result, reply = send(".echo 3#abc")
+	as.Equal(0, result.CmdArgs.SpecialExecuteTimes, "echo does not opt in")
+	as.Equal("3#abc", reply)
+
+	cfg := DefaultConfig()
+	cfg.MaxExecuteTimes = -1
+	as.ErrorContains(cfg.Validate(), "config maxExecuteTimes: must not be negative")
+
+	// 为0时不限制次数
+	cfg.MaxExecuteTimes = 0
+	as.NoError(d.ApplyConfig(cfg))
+	_, reply = send(".13#r d1")
+	as.Contains(reply, "掷骰13次")
+}
@@ -0,0 +1,97 @@
+package dice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+func newExtConflictDice(t *testing.T) (*testDice, func(content string) string) {
+	d := newTestDice(t, nil)
+	send := func(content string) string {
+		return d.send(groupMessage("QQ:1", "admin", "admin", content))
+	}
+	return d, send
+}
+
+func activeExtNames(group *types.GroupInfo) []string {
+	var names []string
+	for _, ext := range group.ActivatedExtList {
+		names = append(names, ext.Name)
+	}
+	return names
+}
+
+func TestExtOnClosesConflictingExt(t *testing.T) {
+	as := assert.New(t)
+	d, send := newExtConflictDice(t)
+
+	send(".ext list")
+	group, ok := d.GroupInfoManager.Load("QQ-Group:1")
+	require.True(t, ok)
+	as.True(group.IsExtensionActive("coc7"))
+	as.False(group.IsExtensionActive("dnd5e"))
+
+	reply := send(".ext on dnd5e")
+	as.Contains(reply, "已开启扩展: dnd5e")
+	as.Contains(reply, "已关闭互斥扩展: coc7")
+	as.Contains(reply, "(dnd5e) 规则")
+	as.False(group.IsExtensionActive("coc7"))
+	as.Equal("dnd5e", group.System)
+	as.Equal("20", group.DiceSideExpr)
+	as.Equal([]string{"core", "dnd5e", "reply", "log"}, activeExtNames(group))
+
+	// 规则切换总是关闭互斥扩展
+	send(".set coc")
+	as.True(group.IsExtensionActive("coc7"))
+	as.False(group.IsExtensionActive("dnd5e"))
+	as.Equal("coc7", group.System)
+}
+
+func TestExtOnRejectsConflictingExt(t *testing.T) {
+	as := assert.New(t)
+	d, send := newExtConflictDice(t)
+	d.Config.ExtConflictReject = true
+
+	reply := send(".ext on dnd5e")
+	as.Contains(reply, "未开启: dnd5e(与 coc7 互斥)")
+	as.NotContains(reply, "已开启扩展")
+
+	group, _ := d.GroupInfoManager.Load("QQ-Group:1")
+	as.True(group.IsExtensionActive("coc7"))
+	as.False(group.IsExtensionActive("dnd5e"))
+	as.Equal("coc7", group.System)
+
+	send(".ext off coc7")
+	as.Contains(send(".ext on dnd5e"), "已开启扩展: dnd5e")
+	as.Equal("dnd5e", group.System)
+}
+
+func TestNewGroupUsesDefaultSystemExt(t *testing.T) {
+	as := assert.New(t)
+	d, send := newExtConflictDice(t)
+	d.Config.DefaultSystem = "dnd5e"
+
+	send(".ext list")
+	group, ok := d.GroupInfoManager.Load("QQ-Group:1")
+	require.True(t, ok)
+	as.True(group.IsExtensionActive("dnd5e"))
+	as.False(group.IsExtensionActive("coc7"), "auto active coc7 conflicts with dnd5e")
+	as.Equal([]string{"core", "dnd5e", "reply", "log"}, activeExtNames(group))
+}
+
+func TestExtOnRequiresGroupAdmin(t *testing.T) {
+	as := assert.New(t)
+	d, send := newExtConflictDice(t)
+	send(".ext list")
+
+	as.Equal("你不是管理员或master", d.send(groupMessage("QQ:2", "member", "", ".ext on dnd5e")))
+
+	group, _ := d.GroupInfoManager.Load("QQ-Group:1")
+	as.True(group.IsExtensionActive("coc7"))
+	as.False(group.IsExtensionActive("dnd5e"))
+	as.Equal("coc7", group.System)
+}
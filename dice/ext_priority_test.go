@@ -0,0 +1,80 @@
+package dice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sealdice/smallseal/dice/exts"
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+func TestExtPriorityDecidesCommandOwner(t *testing.T) {
+	as := assert.New(t)
+	d, send := newExtConflictDice(t)
+	d.RegisterExtension(&types.ExtInfo{
+		Name:       "myra",
+		AutoActive: true,
+		CmdMap: types.CmdMapCls{
+			"ra": &types.CmdItemInfo{
+				Name: "ra",
+				Solve: func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs) types.CmdExecuteResult {
+					exts.ReplyToSender(ctx, msg, "myra")
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				},
+			},
+		},
+	})
+
+	as.NotEqual("myra", send(".ra 50"), "coc7 owns ra by default")
+	as.Equal("当前扩展优先级: core, coc7, reply, log, myra\n(默认顺序)", send(".ext priority"))
+
+	as.Equal("扩展优先级已更新: core, myra, coc7, reply, log", send(".ext priority myra coc7"))
+	as.Equal("myra", send(".ra 50"))
+
+	group, ok := d.GroupInfoManager.Load("QQ-Group:1")
+	require.True(t, ok)
+	as.Equal([]string{"myra", "coc7"}, group.ExtListSnapshot)
+
+	as.Equal("未找到: nope", send(".ext priority nope"))
+	as.Equal([]string{"myra", "coc7"}, group.ExtListSnapshot)
+
+	send(".ext priority clr")
+	as.Empty(group.ExtListSnapshot)
+	as.NotEqual("myra", send(".ra 50"))
+}
+
+func TestCommandOwnerFlagsAreNotBypassed(t *testing.T) {
+	as := assert.New(t)
+	d := newTestDice(t, nil)
+	ping := func(name string, checkBotOn bool) *types.ExtInfo {
+		return &types.ExtInfo{
+			Name:       name,
+			AutoActive: true,
+			CmdMap: types.CmdMapCls{
+				"ping": &types.CmdItemInfo{
+					Name:              "ping",
+					Raw:               true,
+					CheckCurrentBotOn: checkBotOn,
+					Solve: func(ctx *types.MsgContext, msg *types.Message, _ *types.CmdArgs) types.CmdExecuteResult {
+						exts.ReplyToSender(ctx, msg, name)
+						return types.CmdExecuteResult{Matched: true, Solved: true}
+					},
+				},
+			},
+		}
+	}
+	d.RegisterExtension(ping("high", true))
+	d.RegisterExtension(ping("low", false))
+
+	as.Equal("high", d.send(groupMessage("QQ:1", "admin", "admin", ".ping")))
+	d.send(groupMessage("QQ:1", "admin", "admin", ".bot off"))
+
+	// 优先级高的扩展不允许时，不会落到低优先级扩展的同名指令
+	result := d.exec(groupMessage("QQ:1", "admin", "admin", ".ping"))
+	as.Empty(d.texts())
+	as.False(result.Solved)
+	as.Empty(result.Command)
+	as.Empty(result.ExtName)
+}
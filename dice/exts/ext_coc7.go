@@ -64,7 +64,7 @@ func setRuleByName(ctx *types.MsgContext, name string) {
 	}
 
 	if ext := ctx.Dice.ExtFind(name, false); ext != nil {
-		ctx.Group.ExtActive(ext)
+		ctx.Group.ExtActiveExclusive(ext, ctx.Dice.GetExtList())
 	}
 
 	ctx.Group.System = name
@@ -304,10 +304,10 @@ func RegisterBuiltinExtCoc7(theDice types.DiceLike) {
 			var text string
 			if cmdArgs.SpecialExecuteTimes > 1 {
 				VarSetValueInt64(mctx, "$t次数", int64(cmdArgs.SpecialExecuteTimes))
-				// if cmdArgs.SpecialExecuteTimes > int(ctx.Dice.Config.MaxExecuteTime) {
-				// 	ReplyToSender(mctx, msg, DiceFormatTmpl(mctx, "COC:检定_轮数过多警告"))
-				// 	return types.CmdExecuteResult{Matched: true, Solved: true}
-				// }
+				if ctx.MaxExecuteTimes > 0 && cmdArgs.SpecialExecuteTimes > ctx.MaxExecuteTimes {
+					ReplyToSender(mctx, msg, DiceFormatTmpl(mctx, "COC:检定_轮数过多警告"))
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
 
 				texts := []string{}
 				for range cmdArgs.SpecialExecuteTimes {
@@ -439,7 +439,7 @@ func RegisterBuiltinExtCoc7(theDice types.DiceLike) {
 				}
 			}
 
-			ctx.Group.ExtActive(ctx.Dice.ExtFind("coc7", false))
+			ctx.Group.ExtActiveExclusive(ctx.Dice.ExtFind("coc7", false), ctx.Dice.GetExtList())
 			ctx.Group.System = "coc7"
 			ctx.Group.UpdatedAtTime = time.Now().Unix()
 			return types.CmdExecuteResult{Matched: true, Solved: true}
@@ -1247,11 +1247,11 @@ func RegisterBuiltinExtCoc7(theDice types.DiceLike) {
 					},
 				},
 			}
-			ctx.CommandInfo = commandInfo
+			mctx.CommandInfo = commandInfo
 
 			text := DiceFormatTmpl(mctx, "COC:理智检定")
 			if kw := cmdArgs.GetKwarg("ci"); kw != nil {
-				info, err := json.Marshal(ctx.CommandInfo)
+				info, err := json.Marshal(mctx.CommandInfo)
 				if err == nil {
 					text += "\n" + string(info)
 				} else {
@@ -3,6 +3,7 @@ package exts
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -19,6 +20,9 @@ type stubDice struct {
 	templates  *utils.SyncMap[string, *types.GameSystemTemplateV2]
 	replies    []*types.MsgToReply
 	extensions map[string]*types.ExtInfo
+
+	quitsMu sync.Mutex
+	quits   []string
 }
 
 func newStubDice(tmpl *types.GameSystemTemplateV2) *stubDice {
@@ -53,8 +57,9 @@ func (s *stubDice) ExtFind(string, bool) *types.ExtInfo { return nil }
 
 func (s *stubDice) GetExtList() []*types.ExtInfo { return nil }
 
-func (s *stubDice) SendReply(msg *types.MsgToReply) {
+func (s *stubDice) SendReply(msg *types.MsgToReply) error {
 	s.replies = append(s.replies, msg)
+	return nil
 }
 
 func (s *stubDice) RegisterMessageInHook(string, types.HookPriority, types.MessageInHook) (types.HookHandle, error) {
@@ -87,6 +92,28 @@ func (s *stubDice) IsMaster(string) bool { return false }
 
 func (s *stubDice) PersistGroupInfo(string, *types.GroupInfo) {}
 
+func (s *stubDice) SendFile(string, string, string, string) error { return nil }
+
+func (s *stubDice) QuitGroup(adapterID string, groupID string) error {
+	s.quitsMu.Lock()
+	defer s.quitsMu.Unlock()
+	s.quits = append(s.quits, adapterID+"/"+groupID)
+	return nil
+}
+
+func (s *stubDice) ScheduleQuitGroup(adapterID string, groupID string, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		_ = s.QuitGroup(adapterID, groupID)
+	})
+}
+
+// Quits 返回已记录的退群请求，可在其他 goroutine 写入时读取
+func (s *stubDice) Quits() []string {
+	s.quitsMu.Lock()
+	defer s.quitsMu.Unlock()
+	return append([]string(nil), s.quits...)
+}
+
 func minimalTextMap() types.TextTemplateWithWeightDict {
 	toItem := func(text string) types.TextTemplateItem {
 		return types.TextTemplateItem{text, 1}
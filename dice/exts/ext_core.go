@@ -4,17 +4,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/samber/lo"
+	"github.com/sealdice/smallseal/dice/ban"
+	"github.com/sealdice/smallseal/dice/helpdoc"
 	"github.com/sealdice/smallseal/dice/types"
 
 	ds "github.com/sealdice/dicescript"
 )
 
+// botByeDelay .bot bye 发出退群预告后等待多久退群
+var botByeDelay = 5 * time.Second
+
 func RegisterBuiltinExtCore(dice types.DiceLike) {
 	theExt := &types.ExtInfo{
 		Name:       "core",
@@ -186,10 +192,10 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 
 			if cmdArgs.SpecialExecuteTimes > 1 {
 				VarSetValueInt64(ctx, "$t次数", int64(cmdArgs.SpecialExecuteTimes))
-				// if cmdArgs.SpecialExecuteTimes > int(ctx.Dice.Config.MaxExecuteTime) {
-				// 	ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:骰点_轮数过多警告"))
-				// 	return types.CmdExecuteResult{Matched: true, Solved: true}
-				// }
+				if ctx.MaxExecuteTimes > 0 && cmdArgs.SpecialExecuteTimes > ctx.MaxExecuteTimes {
+					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:骰点_轮数过多警告"))
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
 				var texts []string
 				for range cmdArgs.SpecialExecuteTimes {
 					ret := rollOne()
@@ -225,11 +231,9 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 			_ = commandInfo // 避免未使用变量警告
 
 			if kw := cmdArgs.GetKwarg("asm"); r != nil && kw != nil {
-				// TODO: ctx.PrivilegeLevel字段在MsgContext中不存在，暂时注释掉
-				// if ctx.PrivilegeLevel >= 40 {
-				//	asm := r.GetAsmText()
-				//	text += "\n" + asm
-				// }
+				if ctx.PrivilegeLevel >= types.PrivilegeLevelInviter {
+					text += "\n" + ctx.GetVM().GetAsmText()
+				}
 			}
 
 			if kw := cmdArgs.GetKwarg("ci"); kw != nil {
@@ -288,18 +292,11 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 
 	textHelp := ".text <文本模板> // 文本指令，例: .text 看看手气: {1d16}"
 	cmdText := &types.CmdItemInfo{
-		Name:      "text",
-		ShortHelp: textHelp,
-		Help:      "文本模板指令:\n" + textHelp,
+		Name:              "text",
+		ShortHelp:         textHelp,
+		Help:              "文本模板指令:\n" + textHelp,
+		RequiredPrivilege: types.PrivilegeLevelGroupAdmin,
 		Solve: func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs) types.CmdExecuteResult {
-			// if ctx.Dice.Config.TextCmdTrustOnly {
-			// 	// 检查master和信任权限
-			// 	// 拒绝无权限访问
-			// 	if ctx.PrivilegeLevel < 70 {
-			// 		ReplyToSender(ctx, msg, "你不具备Master权限")
-			// 		return types.CmdExecuteResult{Matched: true, Solved: true}
-			// 	}
-			// }
 			if cmdArgs.IsArgEqual(1, "help") {
 				return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
 			}
@@ -316,10 +313,6 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 					text := r.ToString()
 
 					if kw := cmdArgs.GetKwarg("asm"); r != nil && kw != nil {
-						// if ctx.PrivilegeLevel >= 40 {
-						// 	asm := r.GetAsmText()
-						// 	text += "\n" + asm
-						// }
 						text += "\n" + ctx.GetVM().GetAsmText()
 					}
 
@@ -333,6 +326,9 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 
 					if seemsCommand {
 						ReplyToSender(ctx, msg, "你可能在利用text让骰子发出指令文本，这被视为恶意行为并已经记录")
+						if ctx.BanManager != nil {
+							BanAddScoreForSender(ctx, msg, ctx.BanManager.Config().ScoreMalicious, "恶意指令")
+						}
 					} else {
 						ReplyToSender(ctx, msg, text)
 					}
@@ -387,8 +383,8 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 
 	cmdHelp := &types.CmdItemInfo{
 		Name:      "help",
-		ShortHelp: ".help [指令] // 查看帮助",
-		Help:      "帮助:\n.help [指令] // 查看指定指令帮助\n.help // 查看当前可用指令",
+		ShortHelp: ".help [指令/词条] // 查看帮助",
+		Help:      "帮助:\n.help [指令] // 查看指定指令帮助\n.help <词条> // 查看帮助文档中标题完全一致的词条\n.help // 查看当前可用指令\n模糊搜索帮助文档请使用 .find",
 		Solve: func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs) types.CmdExecuteResult {
 			if cmdArgs.IsArgEqual(1, "help") {
 				return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
@@ -428,7 +424,11 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 					ReplyToSender(ctx, msg, text)
 					return types.CmdExecuteResult{Matched: true, Solved: true}
 				}
-				ReplyToSender(ctx, msg, fmt.Sprintf("未找到指令: %s", target))
+				if text, ok := helpdocLookup(ctx, cmdArgs.CleanArgs); ok {
+					ReplyToSender(ctx, msg, text)
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
+				ReplyToSender(ctx, msg, fmt.Sprintf("未找到指令或词条: %s，可使用 .find 搜索帮助文档", target))
 				return types.CmdExecuteResult{Matched: true, Solved: true}
 			}
 
@@ -476,11 +476,168 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 		},
 	}
 
+	findHelp := ".find <关键词> // 模糊搜索帮助文档，多个关键词以空格分隔\n" +
+		".find #<编号> // 查看指定编号的词条"
+	cmdFind := &types.CmdItemInfo{
+		Name:      "find",
+		ShortHelp: findHelp,
+		Help:      "查找帮助文档:\n" + findHelp,
+		Solve: func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs) types.CmdExecuteResult {
+			keyword := strings.TrimSpace(cmdArgs.CleanArgs)
+			if keyword == "" || cmdArgs.IsArgEqual(1, "help") {
+				return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			if ctx.HelpManager == nil {
+				ReplyToSender(ctx, msg, "帮助文档尚未加载")
+				return types.CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			packages, preferred := helpdocPackages(ctx)
+			if rest, ok := strings.CutPrefix(keyword, "#"); ok {
+				if id, err := strconv.Atoi(strings.TrimSpace(rest)); err == nil {
+					if item, ok := ctx.HelpManager.Get(id, packages); ok {
+						ReplyToSender(ctx, msg, helpdocFormatItem(item))
+					} else {
+						ReplyToSender(ctx, msg, fmt.Sprintf("未找到编号为 %d 的词条", id))
+					}
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
+			}
+
+			results := ctx.HelpManager.Search(keyword, packages, preferred, 5)
+			if len(results) == 0 {
+				ReplyToSender(ctx, msg, fmt.Sprintf("未找到与 %s 相关的词条", keyword))
+				return types.CmdExecuteResult{Matched: true, Solved: true}
+			}
+			text := helpdocFormatItem(results[0].Item)
+			if len(results) > 1 {
+				rows := make([]string, 0, len(results)-1)
+				for _, r := range results[1:] {
+					rows = append(rows, fmt.Sprintf("[#%d] %s(%s)", r.Item.ID, r.Item.Title, r.Item.Package))
+				}
+				text += "\n\n其他可能的结果:\n" + strings.Join(rows, "\n") + "\n使用 .find #编号 查看"
+			}
+			ReplyToSender(ctx, msg, text)
+			return types.CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	helpdocHelp := ".helpdoc (list) // 查看帮助文档包及本群启用情况\n" +
+		".helpdoc use <包名> [<包名>...] // 本群只启用指定的文档包\n" +
+		".helpdoc all // 本群启用全部文档包\n" +
+		".helpdoc default <包名>/clr // 设置本群优先的文档包\n" +
+		".helpdoc reload // 重新读取帮助文档(仅master)"
+	cmdHelpdoc := &types.CmdItemInfo{
+		Name:      "helpdoc",
+		ShortHelp: helpdocHelp,
+		Help:      "帮助文档管理:\n" + helpdocHelp,
+		Solve: func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs) types.CmdExecuteResult {
+			if cmdArgs.IsArgEqual(1, "help") {
+				return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			if ctx.HelpManager == nil {
+				ReplyToSender(ctx, msg, "帮助文档尚未加载")
+				return types.CmdExecuteResult{Matched: true, Solved: true}
+			}
+			hm := ctx.HelpManager
+
+			action := strings.ToLower(cmdArgs.GetArgN(1))
+			switch action {
+			case "", "list", "show":
+				pkgs := hm.Packages()
+				if len(pkgs) == 0 {
+					ReplyToSender(ctx, msg, "当前没有任何帮助文档")
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
+				packages, preferred := helpdocPackages(ctx)
+				rows := make([]string, 0, len(pkgs))
+				for _, pkg := range pkgs {
+					row := fmt.Sprintf("%s %d条", pkg.Name, pkg.ItemCount)
+					if len(packages) == 0 || slices.Contains(packages, pkg.Name) {
+						row += " [启用]"
+					}
+					if pkg.Name == preferred {
+						row += " [优先]"
+					}
+					rows = append(rows, row)
+				}
+				ReplyToSender(ctx, msg, "帮助文档包:\n"+strings.Join(rows, "\n"))
+				return types.CmdExecuteResult{Matched: true, Solved: true}
+			case "reload":
+				if !CheckPrivilege(ctx, msg, types.PrivilegeLevelMaster) {
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
+				if err := hm.Reload(); err != nil {
+					ReplyToSender(ctx, msg, "重新读取失败: "+err.Error())
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
+				ReplyToSender(ctx, msg, fmt.Sprintf("已重新读取帮助文档，共%d个文档包", len(hm.Packages())))
+				return types.CmdExecuteResult{Matched: true, Solved: true}
+			case "use", "all", "default":
+			default:
+				return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+
+			if ctx.IsPrivate || ctx.Group == nil {
+				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_私聊不可用"))
+				return types.CmdExecuteResult{Matched: true, Solved: true}
+			}
+			if !CheckPrivilege(ctx, msg, types.PrivilegeLevelGroupAdmin) {
+				return types.CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			var text string
+			switch action {
+			case "use":
+				names := cmdArgs.Args[1:]
+				if len(names) == 0 {
+					return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				var missing []string
+				for _, name := range names {
+					if !hm.HasPackage(name) {
+						missing = append(missing, name)
+					}
+				}
+				if len(missing) > 0 {
+					ReplyToSender(ctx, msg, fmt.Sprintf("未找到: %s", strings.Join(missing, ", ")))
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
+				ctx.Group.HelpPackages = lo.Uniq(names)
+				text = "本群启用的帮助文档包: " + strings.Join(ctx.Group.HelpPackages, ", ")
+			case "all":
+				ctx.Group.HelpPackages = nil
+				text = "本群已启用全部帮助文档包"
+			case "default":
+				name := cmdArgs.GetArgN(2)
+				switch {
+				case name == "":
+					return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				case strings.EqualFold(name, "clr"):
+					ctx.Group.DefaultHelpGroup = ""
+					text = "已清除本群优先的帮助文档包"
+				case !hm.HasPackage(name):
+					ReplyToSender(ctx, msg, fmt.Sprintf("未找到: %s", name))
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				default:
+					ctx.Group.DefaultHelpGroup = name
+					text = "本群优先的帮助文档包: " + name
+				}
+			}
+			ctx.Group.UpdatedAtTime = time.Now().Unix()
+			ctx.Dice.PersistGroupInfo(ctx.Group.GroupId, ctx.Group)
+			ReplyToSender(ctx, msg, text)
+			return types.CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
 	cmdBot := &types.CmdItemInfo{
 		Name:      "bot",
 		ShortHelp: ".bot on/off/about/bye // 管理骰子状态",
 		Help:      "骰子管理:\n.bot on // 开启当前群服务\n.bot off // 关闭当前群服务\n.bot about // 查看骰子信息\n.bot bye // 退群前提示",
-		Raw:       true,
+		// 群内关闭时也要能响应 .bot on，但@其他骰子时交给对方处理
+		Raw:                true,
+		CheckMentionOthers: true,
 		Solve: func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs) types.CmdExecuteResult {
 			if cmdArgs.IsArgEqual(1, "help") {
 				return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
@@ -508,7 +665,11 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_私聊不可用"))
 					return types.CmdExecuteResult{Matched: true, Solved: true}
 				}
-				ctx.Group.Active = true
+				if !CheckPrivilege(ctx, msg, types.PrivilegeLevelGroupAdmin) {
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
+				// 仅开关收到指令的骰子账号，群内的其他账号不受影响
+				ctx.Group.SetDiceActive(ctx.DiceID, true)
 				ctx.IsCurGroupBotOn = true
 				persistGroupState()
 				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:骰子开启"))
@@ -517,7 +678,10 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_私聊不可用"))
 					return types.CmdExecuteResult{Matched: true, Solved: true}
 				}
-				ctx.Group.Active = false
+				if !CheckPrivilege(ctx, msg, types.PrivilegeLevelGroupAdmin) {
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
+				ctx.Group.SetDiceActive(ctx.DiceID, false)
 				ctx.IsCurGroupBotOn = false
 				persistGroupState()
 				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:骰子关闭"))
@@ -526,7 +690,15 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_私聊不可用"))
 					return types.CmdExecuteResult{Matched: true, Solved: true}
 				}
+				if !CheckPrivilege(ctx, msg, types.PrivilegeLevelInviter) {
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
 				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:骰子退群预告"))
+				ctx.Group.RemoveDice(ctx.DiceID)
+				persistGroupState()
+				if ctx.Dice != nil {
+					ctx.Dice.ScheduleQuitGroup(ctx.AdapterId, msg.GroupID, botByeDelay)
+				}
 			case "about":
 				info := "测试用小海豹 v0.1\n海豹核心的精简实现，目前仅有基本骰点和部分coc7指令。\n缺少绝大部分指令，没有数据持久化功能，随时可能关闭，切勿用于跑团"
 				ReplyToSender(ctx, msg, info)
@@ -538,11 +710,12 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 	}
 
 	cmdDismiss := &types.CmdItemInfo{
-		Name:              "dismiss",
-		ShortHelp:         ".dismiss // 退群别名",
-		Help:              "退群(映射到 .bot bye):\n.dismiss",
-		Raw:               true,
-		DisabledInPrivate: true,
+		Name:               "dismiss",
+		ShortHelp:          ".dismiss // 退群别名",
+		Help:               "退群(映射到 .bot bye):\n.dismiss",
+		Raw:                true,
+		CheckMentionOthers: true,
+		DisabledInPrivate:  true,
 		Solve: func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs) types.CmdExecuteResult {
 			if ctx.IsPrivate {
 				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_私聊不可用"))
@@ -565,7 +738,9 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 		Name:      "botlist",
 		ShortHelp: botListHelp,
 		Help:      "机器人列表:\n" + botListHelp,
-		Raw:       true,
+		// 标记对象本身就是被@的其他骰子，不检查@
+		Raw:               true,
+		CheckCurrentBotOn: true,
 		Solve: func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs) types.CmdExecuteResult {
 			if ctx.IsPrivate || ctx.Group == nil {
 				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_私聊不可用"))
@@ -667,6 +842,117 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 		},
 	}
 
+	banHelp := ".ban add <ID> [<原因>] // 拉黑用户或群组\n.ban rm <ID> // 移出黑名单或信任名单\n.ban list // 查看黑名单\n.ban trust <ID> // 设为信任用户\n.ban trust // 查看信任名单\n.ban query <ID> // 查看记录"
+	cmdBan := &types.CmdItemInfo{
+		Name:              "ban",
+		ShortHelp:         banHelp,
+		Help:              "黑名单(仅master可用):\n" + banHelp,
+		RequiredPrivilege: types.PrivilegeLevelMaster,
+		Solve: func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs) types.CmdExecuteResult {
+			if cmdArgs.IsArgEqual(1, "help") {
+				return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			if ctx.BanManager == nil {
+				ReplyToSender(ctx, msg, "黑名单尚未初始化")
+				return types.CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			getTarget := func() (string, string) {
+				if len(cmdArgs.At) > 0 {
+					return cmdArgs.At[0].UserID, cmdArgs.GetRestArgsFrom(2)
+				}
+				return cmdArgs.GetArgN(2), cmdArgs.GetRestArgsFrom(3)
+			}
+			place := msg.GroupID
+			if ctx.IsPrivate || !types.IsGroupScene(msg.MessageType) {
+				place = "私聊"
+			}
+
+			action := strings.ToLower(cmdArgs.GetArgN(1))
+			switch action {
+			case "add":
+				id, reason := getTarget()
+				if id == "" {
+					return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				if reason == "" {
+					reason = "骰主指令"
+				}
+				if _, err := ctx.BanManager.SetRank(id, "", ban.BanRankBanned, place, reason); err != nil {
+					ReplyToSender(ctx, msg, fmt.Sprintf("拉黑失败: %s", err.Error()))
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
+				ReplyToSender(ctx, msg, fmt.Sprintf("已将 %s 加入黑名单，原因: %s", id, reason))
+			case "trust":
+				id, reason := getTarget()
+				if id == "" {
+					items := ctx.BanManager.List(ban.BanRankTrusted)
+					if len(items) == 0 {
+						ReplyToSender(ctx, msg, "信任名单为空")
+						return types.CmdExecuteResult{Matched: true, Solved: true}
+					}
+					rows := make([]string, 0, len(items))
+					for _, item := range items {
+						rows = append(rows, item.ID)
+					}
+					ReplyToSender(ctx, msg, "信任名单:\n"+strings.Join(rows, "\n"))
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
+				if reason == "" {
+					reason = "骰主指令"
+				}
+				if _, err := ctx.BanManager.SetRank(id, "", ban.BanRankTrusted, place, reason); err != nil {
+					ReplyToSender(ctx, msg, fmt.Sprintf("设置失败: %s", err.Error()))
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
+				ReplyToSender(ctx, msg, fmt.Sprintf("已将 %s 设为信任用户", id))
+			case "rm", "del":
+				id, _ := getTarget()
+				if id == "" {
+					return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				removed, err := ctx.BanManager.Delete(id)
+				switch {
+				case err != nil:
+					ReplyToSender(ctx, msg, fmt.Sprintf("移除失败: %s", err.Error()))
+				case removed:
+					ReplyToSender(ctx, msg, fmt.Sprintf("已将 %s 移出黑名单", id))
+				default:
+					ReplyToSender(ctx, msg, fmt.Sprintf("%s 不在黑名单中", id))
+				}
+			case "list", "show", "":
+				items := ctx.BanManager.List(ban.BanRankBanned, ban.BanRankWarn)
+				if len(items) == 0 {
+					ReplyToSender(ctx, msg, "黑名单为空")
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
+				rows := make([]string, 0, len(items))
+				for _, item := range items {
+					rows = append(rows, fmt.Sprintf("[%s] %s 怒气值:%d", item.Rank, item.ID, item.Score))
+				}
+				ReplyToSender(ctx, msg, "黑名单列表:\n"+strings.Join(rows, "\n"))
+			case "query":
+				id, _ := getTarget()
+				if id == "" {
+					return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				item, ok := ctx.BanManager.Get(id)
+				if !ok {
+					ReplyToSender(ctx, msg, fmt.Sprintf("%s 没有任何记录", id))
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
+				text := fmt.Sprintf("%s 当前等级: %s 怒气值: %d", item.ID, item.Rank, item.Score)
+				for idx, reason := range item.Reasons {
+					text += fmt.Sprintf("\n- %s %s: %s", time.Unix(item.Times[idx], 0).Format("2006-01-02 15:04:05"), item.Places[idx], reason)
+				}
+				ReplyToSender(ctx, msg, text)
+			default:
+				return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			return types.CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
 	helpNN := ".nn // 查看当前角色名\n.nn <角色名> // 设置角色名\n.nn clr // 重置为平台昵称"
 	cmdNN := &types.CmdItemInfo{
 		Name:      "nn",
@@ -727,9 +1013,10 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 
 	helpSet := ".set info // 查看当前面数\n.set <面数或表达式> // 设置群默认骰子面数\n.set dnd // 切换到DND模式\n.set coc // 切换到COC模式\n.set clr // 清除群内面数设置"
 	cmdSet := &types.CmdItemInfo{
-		Name:      "set",
-		ShortHelp: helpSet,
-		Help:      "设定骰子面数:\n" + helpSet,
+		Name:              "set",
+		ShortHelp:         helpSet,
+		Help:              "设定骰子面数:\n" + helpSet,
+		RequiredPrivilege: types.PrivilegeLevelGroupAdmin,
 		Solve: func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs) types.CmdExecuteResult {
 			if cmdArgs.IsArgEqual(1, "help") {
 				return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
@@ -790,23 +1077,25 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 						}
 					}
 					if isMatch {
-						ctx.Group.System = key
-						ctx.GameSystem = tmpl
-						ctx.Group.DiceSideExpr = tmpl.Commands.Set.DiceSidesExpr
-						ctx.Group.UpdatedAtTime = time.Now().Unix()
+						setGameSystem(ctx, key, tmpl)
 
 						extNames := []string{}
+						var closed []*types.ExtInfo
 						for _, name := range tmpl.Commands.Set.RelatedExt {
-							// 开启相关扩展
+							// 开启相关扩展，切换规则时总是关闭互斥的扩展
 							ei := ctx.Dice.ExtFind(name, false)
 							extNames = append(extNames, name)
 							if ei != nil {
-								ctx.Group.ExtActive(ei)
-								ctx.Group.ActivatedExtList = ctx.Group.GetActiveExtensions(ctx.Dice.GetExtList())
+								closed = append(closed, ctx.Group.ExtActiveExclusive(ei, ctx.Dice.GetExtList())...)
 							}
 						}
+						ctx.Group.ActivatedExtList = ctx.Group.GetActiveExtensions(ctx.Dice.GetExtList())
 
-						ReplyToSender(ctx, msg, fmt.Sprintf("已切换至 %s(%s) 规则，默认骰子面数 %s，自动启用关联扩展: %s", tmpl.FullName, tmpl.Name, strings.ToUpper(tmpl.Commands.Set.DiceSidesExpr), strings.Join(extNames, ", ")))
+						text := fmt.Sprintf("已切换至 %s(%s) 规则，默认骰子面数 %s，自动启用关联扩展: %s", tmpl.FullName, tmpl.Name, strings.ToUpper(tmpl.Commands.Set.DiceSidesExpr), strings.Join(extNames, ", "))
+						if len(closed) > 0 {
+							text += fmt.Sprintf("\n已关闭互斥扩展: %s", joinExtNames(closed))
+						}
+						ReplyToSender(ctx, msg, text)
 						persistGroupState()
 						found = true
 						return false
@@ -1145,7 +1434,8 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 		},
 	}
 
-	helpExt := ".ext list // 查看所有扩展状态\n.ext on <扩展名> // 开启指定扩展\n.ext off <扩展名> // 关闭指定扩展"
+	helpExt := ".ext list // 查看所有扩展状态\n.ext on <扩展名> // 开启指定扩展\n.ext off <扩展名> // 关闭指定扩展\n" +
+		".ext priority // 查看扩展优先级\n.ext priority <扩展名1> <扩展名2> ... // 设置优先级，同名指令由靠前的扩展处理\n.ext priority clr // 恢复默认优先级"
 	cmdExt := &types.CmdItemInfo{
 		Name:      "ext",
 		ShortHelp: helpExt,
@@ -1175,19 +1465,31 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 				}
 				ReplyToSender(ctx, msg, result.String())
 			case "on":
+				// 开启扩展可能关闭互斥扩展并切换群内规则，与 off 相同需要管理权限
+				if !CheckPrivilege(ctx, msg, types.PrivilegeLevelGroupAdmin) {
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
 				if len(cmdArgs.Args) < 2 {
 					ReplyToSender(ctx, msg, "请指定要开启的扩展名")
 					return types.CmdExecuteResult{Matched: true, Solved: true}
 				}
 				var opened []string
 				var missing []string
+				var closed []*types.ExtInfo
+				var refused []string
+				prevSystem := ctx.Group.System
 				for _, raw := range cmdArgs.Args[1:] {
 					ext := ctx.Dice.ExtFind(raw, false)
 					if ext == nil {
 						missing = append(missing, raw)
 						continue
 					}
-					ctx.Group.ExtActive(ext)
+					c, rejected := extActivate(ctx, ext)
+					if len(rejected) > 0 {
+						refused = append(refused, fmt.Sprintf("%s(与 %s 互斥)", ext.Name, joinExtNames(rejected)))
+						continue
+					}
+					closed = append(closed, c...)
 					opened = append(opened, ext.Name)
 				}
 				ctx.Group.ActivatedExtList = ctx.Group.GetActiveExtensions(ctx.Dice.GetExtList())
@@ -1195,11 +1497,26 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 				if len(opened) > 0 {
 					parts = append(parts, fmt.Sprintf("已开启扩展: %s", strings.Join(opened, ", ")))
 				}
+				if len(closed) > 0 {
+					parts = append(parts, fmt.Sprintf("已关闭互斥扩展: %s", joinExtNames(closed)))
+				}
+				if ctx.Group.System != prevSystem && ctx.GameSystem != nil {
+					parts = append(parts, fmt.Sprintf("已切换至 %s(%s) 规则，默认骰子面数 %s", ctx.GameSystem.FullName, ctx.GameSystem.Name, strings.ToUpper(ctx.Group.DiceSideExpr)))
+				}
+				if len(refused) > 0 {
+					parts = append(parts, fmt.Sprintf("未开启: %s，请先关闭互斥的扩展", strings.Join(refused, ", ")))
+				}
 				if len(missing) > 0 {
 					parts = append(parts, fmt.Sprintf("未找到: %s", strings.Join(missing, ", ")))
 				}
+				if len(opened) > 0 && ctx.Dice != nil {
+					ctx.Dice.PersistGroupInfo(ctx.Group.GroupId, ctx.Group)
+				}
 				ReplyToSender(ctx, msg, strings.Join(parts, "\n"))
 			case "off":
+				if !CheckPrivilege(ctx, msg, types.PrivilegeLevelGroupAdmin) {
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
 				if len(cmdArgs.Args) < 2 {
 					ReplyToSender(ctx, msg, "请指定要关闭的扩展名")
 					return types.CmdExecuteResult{Matched: true, Solved: true}
@@ -1235,6 +1552,42 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 					parts = append(parts, fmt.Sprintf("未找到或不可关闭: %s", strings.Join(failed, ", ")))
 				}
 				ReplyToSender(ctx, msg, strings.Join(parts, "\n"))
+			case "priority":
+				if len(cmdArgs.Args) < 2 {
+					ctx.Group.ActivatedExtList = ctx.Group.GetActiveExtensions(ctx.Dice.GetExtList())
+					text := fmt.Sprintf("当前扩展优先级: %s", joinExtNames(ctx.Group.ActivatedExtList))
+					if len(ctx.Group.ExtListSnapshot) == 0 {
+						text += "\n(默认顺序)"
+					}
+					ReplyToSender(ctx, msg, text)
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
+				if !CheckPrivilege(ctx, msg, types.PrivilegeLevelGroupAdmin) {
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
+				if cmdArgs.IsArgEqual(2, "clr", "reset") {
+					ctx.Group.ExtListSnapshot = nil
+				} else {
+					var order []string
+					var missing []string
+					for _, raw := range cmdArgs.Args[1:] {
+						ext := ctx.Dice.ExtFind(raw, false)
+						if ext == nil {
+							missing = append(missing, raw)
+							continue
+						}
+						order = append(order, ext.Name)
+					}
+					if len(missing) > 0 {
+						ReplyToSender(ctx, msg, fmt.Sprintf("未找到: %s", strings.Join(missing, ", ")))
+						return types.CmdExecuteResult{Matched: true, Solved: true}
+					}
+					ctx.Group.ExtListSnapshot = order
+				}
+				ctx.Group.ActivatedExtList = ctx.Group.GetActiveExtensions(ctx.Dice.GetExtList())
+				ctx.Group.UpdatedAtTime = time.Now().Unix()
+				ctx.Dice.PersistGroupInfo(ctx.Group.GroupId, ctx.Group)
+				ReplyToSender(ctx, msg, fmt.Sprintf("扩展优先级已更新: %s", joinExtNames(ctx.Group.ActivatedExtList)))
 			default:
 				ext := ctx.Dice.ExtFind(action, false)
 				if ext == nil {
@@ -1256,6 +1609,68 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 		},
 	}
 
+	helpWelcome := ".welcome on // 开启入群欢迎\n.welcome off // 关闭入群欢迎\n.welcome show // 查看欢迎语\n.welcome set <欢迎语> // 设置欢迎语，可用{$t新成员}{$t新成员ID}{$t群号}{$t群名}"
+	cmdWelcome := &types.CmdItemInfo{
+		Name:              "welcome",
+		ShortHelp:         helpWelcome,
+		Help:              "入群欢迎:\n" + helpWelcome,
+		DisabledInPrivate: true,
+		RequiredPrivilege: types.PrivilegeLevelGroupAdmin,
+		Solve: func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs) types.CmdExecuteResult {
+			if cmdArgs.IsArgEqual(1, "help") {
+				return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			if ctx.IsPrivate || ctx.Group == nil {
+				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_私聊不可用"))
+				return types.CmdExecuteResult{Matched: true, Solved: true}
+			}
+			persistGroupState := func() {
+				if ctx.Dice != nil {
+					ctx.Dice.PersistGroupInfo(ctx.Group.GroupId, ctx.Group)
+				}
+			}
+
+			switch strings.ToLower(cmdArgs.GetArgN(1)) {
+			case "on":
+				ctx.Group.ShowGroupWelcome = true
+				persistGroupState()
+				ReplyToSender(ctx, msg, "入群欢迎已开启")
+			case "off":
+				ctx.Group.ShowGroupWelcome = false
+				persistGroupState()
+				ReplyToSender(ctx, msg, "入群欢迎已关闭")
+			case "", "show":
+				status := "关闭"
+				if ctx.Group.ShowGroupWelcome {
+					status = "开启"
+				}
+				welcome := ctx.Group.GroupWelcomeMessage
+				if welcome == "" {
+					welcome = "(默认) " + DiceFormatTmpl(ctx, "核心:入群欢迎语_默认")
+				}
+				ReplyToSender(ctx, msg, fmt.Sprintf("入群欢迎: %s\n当前欢迎语: %s", status, welcome))
+			case "set":
+				// 取原始参数，保留欢迎语中的换行
+				text := strings.TrimSpace(cmdArgs.RawArgs)
+				if len(text) >= 3 && strings.EqualFold(text[:3], "set") {
+					text = strings.TrimSpace(text[3:])
+				} else {
+					text, _ = cmdArgs.EatPrefixWith("set")
+				}
+				if text == "" {
+					return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				ctx.Group.GroupWelcomeMessage = text
+				ctx.Group.ShowGroupWelcome = true
+				persistGroupState()
+				ReplyToSender(ctx, msg, "欢迎语已设置，入群欢迎已开启")
+			default:
+				return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			return types.CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
 	cmdMap["r"] = cmdRoll
 	cmdMap["rd"] = cmdRoll
 	cmdMap["roll"] = cmdRoll
@@ -1268,10 +1683,13 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 
 	cmdMap["text"] = cmdText
 	cmdMap["help"] = cmdHelp
+	cmdMap["find"] = cmdFind
+	cmdMap["helpdoc"] = cmdHelpdoc
 	cmdMap["bot"] = cmdBot
 	cmdMap["dismiss"] = cmdDismiss
 	cmdMap["botlist"] = cmdBotList
 	cmdMap["master"] = cmdMaster
+	cmdMap["ban"] = cmdBan
 	cmdMap["nn"] = cmdNN
 	cmdMap["userid"] = cmdUserID
 	cmdMap["角色"] = cmdChar
@@ -1281,8 +1699,125 @@ func RegisterBuiltinExtCore(dice types.DiceLike) {
 	cmdMap["pc"] = cmdChar
 	cmdMap["set"] = cmdSet
 	cmdMap["ext"] = cmdExt
+	cmdMap["welcome"] = cmdWelcome
 
 	theExt.CmdMap = cmdMap
+	theExt.OnGroupMemberJoined = groupWelcome
 
 	dice.RegisterExtension(theExt)
 }
+
+// groupWelcome 新成员入群时按群内设置发送欢迎语
+func groupWelcome(ctx *types.MsgContext, msg *types.Message) {
+	if ctx.Group == nil || !ctx.Group.ShowGroupWelcome {
+		return
+	}
+
+	name := msg.Sender.Nickname
+	if name == "" && ctx.Player != nil {
+		name = ctx.Player.Name
+	}
+	if name == "" {
+		name = msg.Sender.UserID
+	}
+	VarSetValueStr(ctx, "$t新成员", name)
+	VarSetValueStr(ctx, "$t新成员ID", msg.Sender.UserID)
+	VarSetValueStr(ctx, "$t群号", ctx.Group.GroupId)
+	VarSetValueStr(ctx, "$t群名", ctx.Group.GroupName)
+
+	var text string
+	if ctx.Group.GroupWelcomeMessage == "" {
+		text = DiceFormatTmpl(ctx, "核心:入群欢迎语_默认")
+	} else {
+		var err error
+		text, err = DiceFormat(ctx, ctx.Group.GroupWelcomeMessage)
+		if err != nil {
+			return
+		}
+	}
+	if strings.TrimSpace(text) != "" {
+		ReplyGroup(ctx, msg, text)
+	}
+}
+
+// extActivate 在群内开启扩展并处理互斥关系，返回被关闭的互斥扩展。
+// ctx.ExtConflictReject 为真且存在已开启的互斥扩展时不做改动，通过 rejected 返回这些扩展。
+// 开启后若有规则模板的 RelatedExt 关联该扩展，群规则随之切换。
+func extActivate(ctx *types.MsgContext, ext *types.ExtInfo) (closed []*types.ExtInfo, rejected []*types.ExtInfo) {
+	all := ctx.Dice.GetExtList()
+	if ctx.ExtConflictReject {
+		if conflicts := ctx.Group.ExtConflicts(ext, all); len(conflicts) > 0 {
+			return nil, conflicts
+		}
+	}
+	closed = ctx.Group.ExtActiveExclusive(ext, all)
+	if key, tmpl := gameSystemForExt(ctx.Dice, ext.Name); tmpl != nil && !strings.EqualFold(ctx.Group.System, key) {
+		setGameSystem(ctx, key, tmpl)
+	}
+	return closed, nil
+}
+
+// gameSystemForExt 查找 RelatedExt 中包含指定扩展的规则模板
+func gameSystemForExt(d types.DiceLike, extName string) (string, *types.GameSystemTemplateV2) {
+	var foundKey string
+	var found *types.GameSystemTemplateV2
+	d.GameSystemMapGet().Range(func(key string, tmpl *types.GameSystemTemplateV2) bool {
+		for _, name := range tmpl.Commands.Set.RelatedExt {
+			if strings.EqualFold(name, extName) {
+				foundKey, found = key, tmpl
+				return false
+			}
+		}
+		return true
+	})
+	return foundKey, found
+}
+
+// setGameSystem 切换群规则模板，同时更新默认骰子面数
+func setGameSystem(ctx *types.MsgContext, key string, tmpl *types.GameSystemTemplateV2) {
+	ctx.Group.System = key
+	ctx.GameSystem = tmpl
+	ctx.Group.DiceSideExpr = tmpl.Commands.Set.DiceSidesExpr
+	ctx.Group.UpdatedAtTime = time.Now().Unix()
+}
+
+func joinExtNames(list []*types.ExtInfo) string {
+	names := make([]string, 0, len(list))
+	for _, ext := range list {
+		names = append(names, ext.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// helpdocPackages 当前群启用的帮助文档包与优先的文档包，未设置时启用全部
+func helpdocPackages(ctx *types.MsgContext) ([]string, string) {
+	if ctx.Group == nil {
+		return nil, ""
+	}
+	return ctx.Group.HelpPackages, ctx.Group.DefaultHelpGroup
+}
+
+// helpdocLookup 按标题精确查找帮助文档，同名词条只展示第一条并列出其余来源
+func helpdocLookup(ctx *types.MsgContext, title string) (string, bool) {
+	if ctx.HelpManager == nil {
+		return "", false
+	}
+	packages, preferred := helpdocPackages(ctx)
+	items := ctx.HelpManager.Lookup(title, packages, preferred)
+	if len(items) == 0 {
+		return "", false
+	}
+	text := helpdocFormatItem(items[0])
+	if len(items) > 1 {
+		rows := make([]string, 0, len(items)-1)
+		for _, item := range items[1:] {
+			rows = append(rows, fmt.Sprintf("[#%d] %s(%s)", item.ID, item.Title, item.Package))
+		}
+		text += "\n\n同名词条:\n" + strings.Join(rows, "\n")
+	}
+	return text, true
+}
+
+func helpdocFormatItem(item *helpdoc.HelpItem) string {
+	return fmt.Sprintf("[#%d] %s(%s)\n%s", item.ID, item.Title, item.Package, item.Content)
+}
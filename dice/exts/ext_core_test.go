@@ -0,0 +1,46 @@
+package exts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sealdice/smallseal/dice/types"
+	"github.com/sealdice/smallseal/utils"
+)
+
+func TestBotByeQuitsOnlyCurrentAccount(t *testing.T) {
+	prevDelay := botByeDelay
+	botByeDelay = 0
+	defer func() { botByeDelay = prevDelay }()
+
+	ctx, msg, stub := newCoc7TestContext(t)
+	RegisterBuiltinExtCore(stub)
+	cmdBot, ok := stub.extensions["core"].CmdMap["bot"]
+	require.True(t, ok)
+
+	ctx.AdapterId = "qq"
+	ctx.DiceID = types.DiceAccountID("qq", "QQ:1")
+	ctx.Group.Active = true
+	ctx.Group.DiceIDExistsMap = &utils.SyncMap[string, bool]{}
+	ctx.Group.DiceIDExistsMap.Store(ctx.DiceID, true)
+	ctx.Group.DiceIDExistsMap.Store("qq:QQ:2", true)
+	ctx.Group.SetDiceActive("qq:QQ:2", true)
+
+	executeCommandWith(t, stub, ctx, msg, ".bot bye", cmdBot, "bot")
+	assert.Empty(t, stub.Quits(), "members without privilege cannot dismiss the bot")
+
+	ctx.PrivilegeLevel = types.PrivilegeLevelInviter
+	executeCommandWith(t, stub, ctx, msg, ".bot bye", cmdBot, "bot")
+	assert.Eventually(t, func() bool {
+		return len(stub.Quits()) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"qq/" + msg.GroupID}, stub.Quits())
+
+	assert.False(t, ctx.Group.DiceIDExistsMap.Exists(ctx.DiceID))
+	assert.True(t, ctx.Group.DiceIDExistsMap.Exists("qq:QQ:2"))
+	assert.True(t, ctx.Group.IsDiceActive("qq:QQ:2"), "other accounts keep serving the group")
+	assert.True(t, ctx.Group.Active)
+}
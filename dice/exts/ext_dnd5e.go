@@ -471,11 +471,11 @@ func RegisterBuiltinExtDnd5e(dice types.DiceLike) {
 					round = cmdArgs.SpecialExecuteTimes
 				}
 				// 从COC复制来的轮数检查，同时特判一次的情况，防止完全骰不出去点
-				// if cmdArgs.SpecialExecuteTimes > int(ctx.Dice.Config.MaxExecuteTime) && cmdArgs.SpecialExecuteTimes != 1 {
-				// 	VarSetValueStr(mctx, "$t次数", strconv.Itoa(cmdArgs.SpecialExecuteTimes))
-				// 	ReplyToSender(mctx, msg, DiceFormatTmpl(mctx, "DND:检定_轮数过多警告"))
-				// 	return CmdExecuteResult{Matched: true, Solved: true}
-				// }
+				if ctx.MaxExecuteTimes > 0 && cmdArgs.SpecialExecuteTimes > ctx.MaxExecuteTimes && cmdArgs.SpecialExecuteTimes != 1 {
+					VarSetValueStr(mctx, "$t次数", strconv.Itoa(cmdArgs.SpecialExecuteTimes))
+					ReplyToSender(mctx, msg, DiceFormatTmpl(mctx, "DND:检定_轮数过多警告"))
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
 				// commandInfo配置
 				var commandInfo = map[string]interface{}{
 					"cmd":    "rc",
@@ -0,0 +1,377 @@
+package exts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sealdice/smallseal/dice/gamelog"
+	"github.com/sealdice/smallseal/dice/types"
+	"github.com/sealdice/smallseal/utils"
+)
+
+// logFileNameReplacer 去掉群号与日志名中不适合出现在文件名里的字符
+var logFileNameReplacer = strings.NewReplacer(":", "_", "/", "_", "\\", "_", " ", "_")
+
+// logExportFile 渲染日志并写入导出目录，返回文件路径
+func logExportFile(ctx *types.MsgContext, lm *gamelog.LogManager, groupID string, name string, format string) (string, int, error) {
+	items, err := lm.Store().Items(groupID, name)
+	if err != nil {
+		return "", 0, err
+	}
+	// 骰子回复没有昵称，导出时使用骰子名字
+	diceName := DiceFormatTmpl(ctx, "核心:骰子名字")
+	for _, item := range items {
+		if item.IsDice && item.Nickname == "" {
+			item.Nickname = diceName
+		}
+	}
+	data, err := gamelog.Render(format, name, items)
+	if err != nil {
+		return "", 0, err
+	}
+
+	dir := ctx.LogExportDir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "smallseal-logs")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", 0, err
+	}
+	path := filepath.Join(dir, logFileNameReplacer.Replace(groupID+"_"+name)+"."+format)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", 0, err
+	}
+	return path, len(items), nil
+}
+
+// logSendFile 将导出的文件发到群内，适配器不支持时给出文件位置
+func logSendFile(ctx *types.MsgContext, msg *types.Message, path string) string {
+	if err := ctx.Dice.SendFile(ctx.AdapterId, "group", msg.GroupID, path); err != nil {
+		return fmt.Sprintf("日志文件发送失败(%s)，文件已保存至: %s", err.Error(), path)
+	}
+	return "日志文件已发送"
+}
+
+func RegisterBuiltinExtLog(dice types.DiceLike, lm *gamelog.LogManager) {
+	// 正在记录的群，key 为群号，value 为日志名
+	// 由入站钩子根据群信息同步，出站钩子据此判断回复是否需要记录
+	sessions := &utils.SyncMap[string, string]{}
+
+	syncSession := func(ctx *types.MsgContext) (string, bool) {
+		group := ctx.Group
+		if group == nil || !types.IsGroupScene(ctx.MessageScene) {
+			return "", false
+		}
+		if !group.LogOn || group.LogCurName == "" || !group.IsExtensionActive("log") {
+			sessions.Delete(group.GroupId)
+			return "", false
+		}
+		sessions.Store(group.GroupId, group.LogCurName)
+		return group.LogCurName, true
+	}
+
+	_, _ = dice.RegisterMessageInHook("log", types.HookPriorityLow, func(_ types.DiceLike, _ string, msg *types.Message, ctx *types.MsgContext) types.HookResult {
+		if ctx == nil {
+			return types.HookResultContinue
+		}
+		name, ok := syncSession(ctx)
+		if !ok {
+			return types.HookResultContinue
+		}
+		groupID := ctx.Group.GroupId
+		item := &gamelog.LogItem{
+			Nickname: msg.Sender.Nickname,
+			UserID:   msg.Sender.UserID,
+			Time:     time.Now().UnixMilli(),
+			Message:  msg.Segments.ToText(),
+		}
+		// 指令编号在钩子之后才分配，执行完毕时写入
+		// .log off/end 等指令本身停止了记录，不会出现在日志中
+		ctx.AfterExecute(func() {
+			if !ctx.Group.LogOn || ctx.Group.LogCurName != name {
+				return
+			}
+			item.CommandID = ctx.CommandId
+			err := lm.Store().Append(groupID, name, item)
+			if errors.Is(err, gamelog.ErrLogNotFound) {
+				// 日志已不在存储中，如重启后内存存储被清空，停止记录并告知群内
+				ctx.Group.LogOn = false
+				ctx.Group.LogCurName = ""
+				sessions.Delete(groupID)
+				ReplyGroup(ctx, msg, fmt.Sprintf("日志 %s 已不存在，停止记录", name))
+			}
+			// 其他错误多为存储暂时不可用，下一条消息照常尝试写入
+		})
+		return types.HookResultContinue
+	})
+
+	_, _ = dice.RegisterMessageOutHook("log", types.HookPriorityLow, func(_ types.DiceLike, _ string, reply *types.MsgToReply) types.HookResult {
+		if reply.MessageType != "group" || reply.SendTo.GroupId == "" {
+			return types.HookResultContinue
+		}
+		name, ok := sessions.Load(reply.SendTo.GroupId)
+		if !ok {
+			return types.HookResultContinue
+		}
+		err := lm.Store().Append(reply.SendTo.GroupId, name, &gamelog.LogItem{
+			Nickname:  reply.Sender.Nickname,
+			UserID:    reply.Sender.UserId,
+			Time:      time.Now().UnixMilli(),
+			Message:   reply.Segments.ToText(),
+			IsDice:    true,
+			CommandID: reply.CommandId,
+
+			CommandInfo: reply.CommandInfo,
+		})
+		if errors.Is(err, gamelog.ErrLogNotFound) {
+			// 群信息中的记录状态由入站钩子清除
+			sessions.Delete(reply.SendTo.GroupId)
+		}
+		return types.HookResultContinue
+	})
+
+	formatHelp := strings.Join(gamelog.Formats, "/")
+	logHelp := ".log new [<日志名>] // 新建日志并开始记录\n" +
+		".log on [<日志名>] // 继续记录当前或指定的日志\n" +
+		".log off // 暂停记录\n" +
+		".log end [" + formatHelp + "] // 结束记录并导出文件\n" +
+		".log list // 查看本群的日志\n" +
+		".log get <日志名> [" + formatHelp + "] // 导出指定日志\n" +
+		".log del <日志名> // 删除日志(需要管理权限)\n" +
+		"导出格式默认为 " + gamelog.Formats[0]
+
+	cmdLog := &types.CmdItemInfo{
+		Name:              "log",
+		ShortHelp:         logHelp,
+		Help:              "跑团日志:\n" + logHelp,
+		DisabledInPrivate: true,
+		Solve: func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs) types.CmdExecuteResult {
+			if cmdArgs.IsArgEqual(1, "help") {
+				return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			group := ctx.Group
+			store := lm.Store()
+			persist := func() {
+				if group.LogOn && group.LogCurName != "" {
+					sessions.Store(group.GroupId, group.LogCurName)
+				} else {
+					sessions.Delete(group.GroupId)
+				}
+				group.UpdatedAtTime = time.Now().Unix()
+				ctx.Dice.PersistGroupInfo(group.GroupId, group)
+			}
+			exportFormat := func(n int) (string, bool) {
+				format := strings.ToLower(cmdArgs.GetArgN(n))
+				if format == "" {
+					return gamelog.Formats[0], true
+				}
+				return format, gamelog.IsFormat(format)
+			}
+			export := func(name string, format string) string {
+				path, size, err := logExportFile(ctx, lm, group.GroupId, name, format)
+				if err != nil {
+					return fmt.Sprintf("日志 %s 导出失败: %s", name, err.Error())
+				}
+				return fmt.Sprintf("日志 %s 共%d条记录\n%s", name, size, logSendFile(ctx, msg, path))
+			}
+
+			switch strings.ToLower(cmdArgs.GetArgN(1)) {
+			case "new":
+				if group.LogOn && group.LogCurName != "" {
+					ReplyToSender(ctx, msg, fmt.Sprintf("日志 %s 正在记录中，请先使用 .log end 结束", group.LogCurName))
+					break
+				}
+				name := cmdArgs.GetArgN(2)
+				if name == "" {
+					name = time.Now().Format("20060102_150405")
+				}
+				if err := store.Create(group.GroupId, name); err != nil {
+					if errors.Is(err, gamelog.ErrLogExists) {
+						ReplyToSender(ctx, msg, fmt.Sprintf("日志 %s 已存在，可使用 .log on %s 继续记录", name, name))
+					} else {
+						ReplyToSender(ctx, msg, "新建日志失败: "+err.Error())
+					}
+					break
+				}
+				group.LogCurName = name
+				group.LogOn = true
+				persist()
+				ReplyToSender(ctx, msg, fmt.Sprintf("新日志 %s 已开始记录", name))
+			case "on":
+				name := cmdArgs.GetArgN(2)
+				if name == "" {
+					name = group.LogCurName
+				}
+				if name == "" {
+					ReplyToSender(ctx, msg, "当前没有日志，请使用 .log new 新建")
+					break
+				}
+				if _, err := store.Items(group.GroupId, name); err != nil {
+					ReplyToSender(ctx, msg, fmt.Sprintf("未找到日志 %s", name))
+					break
+				}
+				group.LogCurName = name
+				group.LogOn = true
+				persist()
+				ReplyToSender(ctx, msg, fmt.Sprintf("日志 %s 继续记录", name))
+			case "off":
+				if !group.LogOn {
+					ReplyToSender(ctx, msg, "当前没有正在记录的日志")
+					break
+				}
+				group.LogOn = false
+				persist()
+				ReplyToSender(ctx, msg, fmt.Sprintf("日志 %s 已暂停，可使用 .log on 继续", group.LogCurName))
+			case "end":
+				format, ok := exportFormat(2)
+				if !ok {
+					return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				name := group.LogCurName
+				if name == "" {
+					ReplyToSender(ctx, msg, "当前没有日志，请使用 .log new 新建")
+					break
+				}
+				group.LogOn = false
+				group.LogCurName = ""
+				persist()
+				ReplyToSender(ctx, msg, "记录结束，"+export(name, format))
+			case "get":
+				name := cmdArgs.GetArgN(2)
+				format, ok := exportFormat(3)
+				if name == "" || !ok {
+					return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				ReplyToSender(ctx, msg, export(name, format))
+			case "list", "":
+				infos, err := store.List(group.GroupId)
+				if err != nil {
+					ReplyToSender(ctx, msg, "读取日志列表失败: "+err.Error())
+					break
+				}
+				if len(infos) == 0 {
+					ReplyToSender(ctx, msg, "本群还没有日志")
+					break
+				}
+				rows := make([]string, 0, len(infos))
+				for _, info := range infos {
+					row := fmt.Sprintf("%s %d条 %s", info.Name, info.Size, time.Unix(info.CreatedAt, 0).Format("2006-01-02 15:04"))
+					if info.Name == group.LogCurName {
+						if group.LogOn {
+							row += " [记录中]"
+						} else {
+							row += " [已暂停]"
+						}
+					}
+					rows = append(rows, row)
+				}
+				ReplyToSender(ctx, msg, "本群日志:\n"+strings.Join(rows, "\n"))
+			case "del", "rm":
+				if !CheckPrivilege(ctx, msg, types.PrivilegeLevelGroupAdmin) {
+					break
+				}
+				name := cmdArgs.GetArgN(2)
+				if name == "" {
+					return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				removed, err := store.Delete(group.GroupId, name)
+				switch {
+				case err != nil:
+					ReplyToSender(ctx, msg, "删除失败: "+err.Error())
+				case !removed:
+					ReplyToSender(ctx, msg, fmt.Sprintf("未找到日志 %s", name))
+				default:
+					if name == group.LogCurName {
+						group.LogOn = false
+						group.LogCurName = ""
+						persist()
+					}
+					ReplyToSender(ctx, msg, fmt.Sprintf("已删除日志 %s", name))
+				}
+			default:
+				return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			return types.CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	statHelp := ".stat session [<日志名>] // 统计本次日志中各角色的检定情况，默认为当前日志"
+	cmdStat := &types.CmdItemInfo{
+		Name:              "stat",
+		ShortHelp:         statHelp,
+		Help:              "跑团统计:\n" + statHelp + "\n统计 coc7 的 ra/rc 与 sc 指令，暗骰不计入",
+		DisabledInPrivate: true,
+		Solve: func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs) types.CmdExecuteResult {
+			if !cmdArgs.IsArgEqual(1, "session") {
+				return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			group := ctx.Group
+			name := cmdArgs.GetArgN(2)
+			if name == "" {
+				name = group.LogCurName
+			}
+			if name == "" {
+				// 没有进行中的日志时，统计最近的一份
+				infos, _ := lm.Store().List(group.GroupId)
+				if len(infos) == 0 {
+					ReplyToSender(ctx, msg, "本群还没有日志，请使用 .log new 新建")
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
+				name = infos[len(infos)-1].Name
+			}
+			items, err := lm.Store().Items(group.GroupId, name)
+			if err != nil {
+				ReplyToSender(ctx, msg, fmt.Sprintf("未找到日志 %s", name))
+				return types.CmdExecuteResult{Matched: true, Solved: true}
+			}
+			ReplyToSender(ctx, msg, formatLogStats(gamelog.Stat(name, items)))
+			return types.CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	theExt := &types.ExtInfo{
+		Name:       "log",
+		Version:    "1.0.0",
+		Brief:      "跑团日志模块，记录群内消息与骰子回复并导出为文件，可统计检定情况",
+		Author:     "SealDice-Team",
+		AutoActive: true,
+		Official:   true,
+		CmdMap: types.CmdMapCls{
+			"log":  cmdLog,
+			"stat": cmdStat,
+		},
+	}
+
+	dice.RegisterExtension(theExt)
+}
+
+// formatLogStats 将统计结果排成表格
+func formatLogStats(stats *gamelog.SessionStats) string {
+	if len(stats.Players) == 0 {
+		return fmt.Sprintf("日志 %s 中还没有检定记录", stats.Name)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "日志 %s 检定统计:\n", stats.Name)
+	sb.WriteString("角色 | 检定 | 成功率 | 大成功 | 大失败 | 理智损失 | 平均d100")
+	for _, p := range stats.Players {
+		avg := "-"
+		if p.D100Count > 0 {
+			avg = fmt.Sprintf("%.1f", p.AvgD100)
+		}
+		fmt.Fprintf(&sb, "\n%s | %d | %.0f%% | %d | %d | %d | %s",
+			p.Name, p.Checks, p.SuccessRate*100, p.CriticalSuccesses, p.Fumbles, p.SanLost, avg)
+	}
+	sb.WriteString("\n技能成功率:")
+	for _, p := range stats.Players {
+		skills := make([]string, 0, len(p.Skills))
+		for _, s := range p.Skills {
+			skills = append(skills, fmt.Sprintf("%s %d/%d", s.Name, s.Successes, s.Checks))
+		}
+		fmt.Fprintf(&sb, "\n%s: %s", p.Name, strings.Join(skills, ", "))
+	}
+	return sb.String()
+}
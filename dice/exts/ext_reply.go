@@ -0,0 +1,519 @@
+package exts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/rand"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sealdice/smallseal/dice/types"
+	"github.com/sealdice/smallseal/utils"
+)
+
+// 自定义回复的匹配方式
+const (
+	ReplyMatchExact    = "exact"    // 全文相等
+	ReplyMatchPrefix   = "prefix"   // 前缀
+	ReplyMatchContains = "contains" // 包含
+	ReplyMatchRegex    = "regex"    // 正则，捕获组可用 $t0 $t1 ... 读取
+)
+
+// ReplyConfig 自定义回复配置，对应一个YAML文件
+type ReplyConfig struct {
+	Items []*ReplyRule `yaml:"items"`
+}
+
+// ReplyRule 自定义回复规则，按顺序匹配，只有第一条命中的规则会回复
+type ReplyRule struct {
+	Name      string      `yaml:"name"`                // 规则名，需唯一，用于管理与冷却计时
+	Disabled  bool        `yaml:"disabled,omitempty"`  // 是否停用
+	Match     string      `yaml:"match"`               // 匹配方式，见 ReplyMatch* 常量
+	Keyword   string      `yaml:"keyword"`             // 关键词或正则表达式
+	Condition string      `yaml:"condition,omitempty"` // 附加条件，DiceScript表达式，结果为真时才回复
+	Cooldown  float64     `yaml:"cooldown,omitempty"`  // 冷却时间(秒)，按群计算
+	Replies   []ReplyText `yaml:"replies"`             // 回复文本，按权重随机选取一条
+
+	re *regexp.Regexp
+}
+
+// ReplyText 带权重的回复文本，权重不大于0时视为1
+type ReplyText struct {
+	Text   string `yaml:"text"`
+	Weight int    `yaml:"weight,omitempty"`
+}
+
+// Init 校验规则并预编译正则
+func (r *ReplyRule) Init() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return errors.New("规则名不能为空")
+	}
+	if r.Keyword == "" {
+		return fmt.Errorf("规则 %s: 关键词不能为空", r.Name)
+	}
+	if len(r.Replies) == 0 {
+		return fmt.Errorf("规则 %s: 至少需要一条回复", r.Name)
+	}
+	r.re = nil
+	switch r.Match {
+	case ReplyMatchExact, ReplyMatchPrefix, ReplyMatchContains:
+	case ReplyMatchRegex:
+		re, err := regexp.Compile(r.Keyword)
+		if err != nil {
+			return fmt.Errorf("规则 %s: 正则表达式错误: %w", r.Name, err)
+		}
+		r.re = re
+	default:
+		return fmt.Errorf("规则 %s: 未知的匹配方式 %q，可用 exact/prefix/contains/regex", r.Name, r.Match)
+	}
+	return nil
+}
+
+// match 检查文本是否命中，命中时返回正则捕获组(非正则时为全文)
+func (r *ReplyRule) match(text string) ([]string, bool) {
+	switch r.Match {
+	case ReplyMatchExact:
+		return []string{text}, text == r.Keyword
+	case ReplyMatchPrefix:
+		return []string{text}, strings.HasPrefix(text, r.Keyword)
+	case ReplyMatchContains:
+		return []string{text}, strings.Contains(text, r.Keyword)
+	case ReplyMatchRegex:
+		if r.re == nil {
+			return nil, false
+		}
+		m := r.re.FindStringSubmatch(text)
+		return m, m != nil
+	}
+	return nil, false
+}
+
+// pickReply 按权重随机选取一条回复
+func (r *ReplyRule) pickReply() string {
+	total := 0
+	for _, item := range r.Replies {
+		total += max(item.Weight, 1)
+	}
+	n := rand.Intn(total)
+	for _, item := range r.Replies {
+		n -= max(item.Weight, 1)
+		if n < 0 {
+			return item.Text
+		}
+	}
+	return r.Replies[len(r.Replies)-1].Text
+}
+
+// Init 校验全部规则，规则名不可重复
+func (c *ReplyConfig) Init() error {
+	names := map[string]bool{}
+	for _, rule := range c.Items {
+		if rule == nil {
+			return errors.New("规则不能为空")
+		}
+		if err := rule.Init(); err != nil {
+			return err
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("规则名重复: %s", rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	return nil
+}
+
+// LoadReplyConfigFromData 从YAML内容加载自定义回复配置
+func LoadReplyConfigFromData(data []byte) (*ReplyConfig, error) {
+	cfg := &ReplyConfig{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal YAML: %w", err)
+	}
+	if err := cfg.Init(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadReplyConfig 从YAML文件加载自定义回复配置，文件不存在时返回空配置
+func LoadReplyConfig(filename string) (*ReplyConfig, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return &ReplyConfig{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return LoadReplyConfigFromData(data)
+}
+
+// SaveReplyConfig 将自定义回复配置保存到YAML文件
+func SaveReplyConfig(cfg *ReplyConfig, filename string) error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// ReplyStore 自定义回复规则的存储，规则只整体替换不原地修改，因此读到的规则可以放心使用
+// 设置了文件路径时，通过 .reply 指令做出的修改会写回文件
+type ReplyStore struct {
+	mu   sync.RWMutex
+	path string
+	cfg  *ReplyConfig
+}
+
+func NewReplyStore() *ReplyStore {
+	return &ReplyStore{cfg: &ReplyConfig{}}
+}
+
+// Set 校验并替换当前规则与文件路径，path 为空时修改只保存在内存中
+func (s *ReplyStore) Set(path string, cfg *ReplyConfig) error {
+	if cfg == nil {
+		cfg = &ReplyConfig{}
+	}
+	if err := cfg.Init(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.path = path
+	s.cfg = cfg
+	return nil
+}
+
+// SetPath 只替换文件路径，保留当前规则
+func (s *ReplyStore) SetPath(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.path = path
+}
+
+// Path 当前的规则文件路径
+func (s *ReplyStore) Path() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.path
+}
+
+// Rules 当前全部规则
+func (s *ReplyStore) Rules() []*ReplyRule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg.Items
+}
+
+// Reload 重新读取规则文件，失败时保持原规则
+func (s *ReplyStore) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.path == "" {
+		return errors.New("未设置自定义回复文件")
+	}
+	cfg, err := LoadReplyConfig(s.path)
+	if err != nil {
+		return err
+	}
+	s.cfg = cfg
+	return nil
+}
+
+// update 在规则副本上修改并校验，成功后替换当前规则并写回文件
+func (s *ReplyStore) update(fn func(items []*ReplyRule) ([]*ReplyRule, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := make([]*ReplyRule, len(s.cfg.Items))
+	for i, rule := range s.cfg.Items {
+		cp := *rule
+		cp.Replies = slices.Clone(rule.Replies)
+		items[i] = &cp
+	}
+	items, err := fn(items)
+	if err != nil {
+		return err
+	}
+	cfg := &ReplyConfig{Items: items}
+	if err := cfg.Init(); err != nil {
+		return err
+	}
+	if s.path != "" {
+		if err := SaveReplyConfig(cfg, s.path); err != nil {
+			return err
+		}
+	}
+	s.cfg = cfg
+	return nil
+}
+
+func findReplyRule(items []*ReplyRule, name string) int {
+	return slices.IndexFunc(items, func(rule *ReplyRule) bool {
+		return rule.Name == name
+	})
+}
+
+// splitReplyArgs 按空白切分出前 n-1 段，最后一段保留原文(含换行)
+func splitReplyArgs(s string, n int) []string {
+	var parts []string
+	s = strings.TrimSpace(s)
+	for len(parts) < n-1 && s != "" {
+		idx := strings.IndexFunc(s, func(r rune) bool { return r == ' ' || r == '\t' || r == '\n' || r == '\r' })
+		if idx < 0 {
+			break
+		}
+		parts = append(parts, s[:idx])
+		s = strings.TrimSpace(s[idx:])
+	}
+	if s != "" {
+		parts = append(parts, s)
+	}
+	return parts
+}
+
+// replyCheckCondition 计算规则的附加条件，出错视为不满足
+func replyCheckCondition(ctx *types.MsgContext, cond string) bool {
+	if strings.TrimSpace(cond) == "" {
+		return true
+	}
+	v := ctx.Eval(cond, nil)
+	if v == nil || ctx.GetVM().Error != nil {
+		ctx.GetVM().Error = nil
+		return false
+	}
+	return v.AsBool()
+}
+
+// replyTryRule 尝试用一条规则回复消息，返回是否已回复
+func replyTryRule(ctx *types.MsgContext, msg *types.Message, rule *ReplyRule, text string, now float64) bool {
+	if rule.Disabled {
+		return false
+	}
+	groups, ok := rule.match(text)
+	if !ok {
+		return false
+	}
+	if rule.Cooldown > 0 {
+		if last, ok := ctx.Group.LastCustomReplyTime.Load(rule.Name); ok && now-last < rule.Cooldown {
+			return false
+		}
+	}
+
+	SetTempVars(ctx, "")
+	for i, g := range groups {
+		VarSetValueStr(ctx, "$t"+strconv.Itoa(i), g)
+	}
+	if !replyCheckCondition(ctx, rule.Condition) {
+		return false
+	}
+
+	reply, err := DiceFormat(ctx, rule.pickReply())
+	if err != nil {
+		reply = fmt.Sprintf("自定义回复 %s 执行出错: %s", rule.Name, err.Error())
+	}
+	ctx.Group.LastCustomReplyTime.Store(rule.Name, now)
+	if strings.TrimSpace(reply) != "" {
+		ReplyToSender(ctx, msg, reply)
+	}
+	return true
+}
+
+func replyRuleDesc(idx int, rule *ReplyRule) string {
+	state := "开"
+	if rule.Disabled {
+		state = "关"
+	}
+	text := fmt.Sprintf("%d. [%s] %s %s:%s 回复%d条", idx+1, state, rule.Name, rule.Match, rule.Keyword, len(rule.Replies))
+	if rule.Condition != "" {
+		text += " 条件:" + rule.Condition
+	}
+	if rule.Cooldown > 0 {
+		text += fmt.Sprintf(" 冷却:%gs", rule.Cooldown)
+	}
+	return text
+}
+
+func RegisterBuiltinExtReply(dice types.DiceLike, store *ReplyStore) {
+	replyHelp := ".reply (list) // 查看自定义回复规则\n" +
+		".reply add <名称> <exact|prefix|contains|regex> <关键词> <回复文本> // 添加规则\n" +
+		".reply text <名称> <回复文本> // 为规则追加一条回复\n" +
+		".reply cd <名称> <秒数> // 设置规则冷却时间\n" +
+		".reply on/off <名称> // 启用/停用规则\n" +
+		".reply del <名称> // 删除规则\n" +
+		".reply reload // 重新读取规则文件\n" +
+		"附加条件、回复权重请在规则文件中编辑"
+
+	cmdReply := &types.CmdItemInfo{
+		Name:              "reply",
+		ShortHelp:         replyHelp,
+		Help:              "自定义回复(仅master可用):\n" + replyHelp,
+		RequiredPrivilege: types.PrivilegeLevelMaster,
+		Solve: func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs) types.CmdExecuteResult {
+			if cmdArgs.IsArgEqual(1, "help") {
+				return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+
+			args := splitReplyArgs(cmdArgs.RawArgs, 5)
+			action := ""
+			if len(args) > 0 {
+				action = strings.ToLower(args[0])
+			}
+			argN := func(n int) string {
+				if len(args) > n {
+					return args[n]
+				}
+				return ""
+			}
+			// 写回文件失败等情况统一在这里回复
+			doUpdate := func(okText string, fn func(items []*ReplyRule) ([]*ReplyRule, error)) {
+				if err := store.update(fn); err != nil {
+					ReplyToSender(ctx, msg, "修改失败: "+err.Error())
+					return
+				}
+				ReplyToSender(ctx, msg, okText)
+			}
+			notFound := func(name string) error {
+				return fmt.Errorf("未找到规则 %s", name)
+			}
+
+			switch action {
+			case "", "list", "show":
+				rules := store.Rules()
+				if len(rules) == 0 {
+					ReplyToSender(ctx, msg, "当前没有自定义回复规则")
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
+				rows := make([]string, 0, len(rules))
+				for idx, rule := range rules {
+					rows = append(rows, replyRuleDesc(idx, rule))
+				}
+				ReplyToSender(ctx, msg, "自定义回复规则:\n"+strings.Join(rows, "\n"))
+			case "add":
+				if len(args) < 5 {
+					return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				rule := &ReplyRule{
+					Name:    args[1],
+					Match:   strings.ToLower(args[2]),
+					Keyword: args[3],
+					Replies: []ReplyText{{Text: args[4], Weight: 1}},
+				}
+				doUpdate("已添加自定义回复: "+rule.Name, func(items []*ReplyRule) ([]*ReplyRule, error) {
+					if findReplyRule(items, rule.Name) >= 0 {
+						return nil, fmt.Errorf("规则 %s 已存在", rule.Name)
+					}
+					return append(items, rule), nil
+				})
+			case "text":
+				args = splitReplyArgs(cmdArgs.RawArgs, 3)
+				if len(args) < 3 {
+					return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				name, text := args[1], args[2]
+				doUpdate("已为 "+name+" 追加回复", func(items []*ReplyRule) ([]*ReplyRule, error) {
+					idx := findReplyRule(items, name)
+					if idx < 0 {
+						return nil, notFound(name)
+					}
+					items[idx].Replies = append(items[idx].Replies, ReplyText{Text: text, Weight: 1})
+					return items, nil
+				})
+			case "cd", "cooldown":
+				name := argN(1)
+				seconds, err := strconv.ParseFloat(argN(2), 64)
+				if name == "" || err != nil || seconds < 0 {
+					return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				doUpdate(fmt.Sprintf("%s 的冷却时间已设为 %g 秒", name, seconds), func(items []*ReplyRule) ([]*ReplyRule, error) {
+					idx := findReplyRule(items, name)
+					if idx < 0 {
+						return nil, notFound(name)
+					}
+					items[idx].Cooldown = seconds
+					return items, nil
+				})
+			case "on", "off":
+				name := argN(1)
+				if name == "" {
+					return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				disabled := action == "off"
+				okText := "已启用自定义回复: " + name
+				if disabled {
+					okText = "已停用自定义回复: " + name
+				}
+				doUpdate(okText, func(items []*ReplyRule) ([]*ReplyRule, error) {
+					idx := findReplyRule(items, name)
+					if idx < 0 {
+						return nil, notFound(name)
+					}
+					items[idx].Disabled = disabled
+					return items, nil
+				})
+			case "del", "rm":
+				name := argN(1)
+				if name == "" {
+					return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+				}
+				doUpdate("已删除自定义回复: "+name, func(items []*ReplyRule) ([]*ReplyRule, error) {
+					idx := findReplyRule(items, name)
+					if idx < 0 {
+						return nil, notFound(name)
+					}
+					return slices.Delete(items, idx, idx+1), nil
+				})
+			case "reload":
+				if err := store.Reload(); err != nil {
+					ReplyToSender(ctx, msg, "重新读取失败: "+err.Error())
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				}
+				ReplyToSender(ctx, msg, fmt.Sprintf("已重新读取自定义回复，共%d条规则", len(store.Rules())))
+			default:
+				return types.CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			return types.CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	theExt := &types.ExtInfo{
+		Name:       "reply",
+		Version:    "1.0.0",
+		Brief:      "自定义回复模块，按关键词、正则与附加条件回复非指令消息",
+		Author:     "SealDice-Team",
+		AutoActive: true,
+		Official:   true,
+		OnNotCommandReceived: func(ctx *types.MsgContext, msg *types.Message) {
+			// 钩子对指令消息同样会调用，带有指令编号的消息不参与匹配
+			if ctx.Group == nil || ctx.CommandId != 0 {
+				return
+			}
+			rules := store.Rules()
+			if len(rules) == 0 {
+				return
+			}
+			if ctx.Group.LastCustomReplyTime == nil {
+				ctx.Group.LastCustomReplyTime = &utils.SyncMap[string, float64]{}
+			}
+			text := strings.TrimSpace(msg.Message)
+			now := float64(time.Now().UnixMilli()) / 1000
+			for _, rule := range rules {
+				if replyTryRule(ctx, msg, rule, text, now) {
+					return
+				}
+			}
+		},
+		CmdMap: types.CmdMapCls{
+			"reply": cmdReply,
+		},
+	}
+
+	dice.RegisterExtension(theExt)
+}
@@ -0,0 +1,28 @@
+package exts
+
+import (
+	"fmt"
+
+	"github.com/sealdice/smallseal/dice/ban"
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+// BanAddScoreForSender 为消息发送者增加怒气值，因此被拉黑时提醒当事人
+func BanAddScoreForSender(ctx *types.MsgContext, msg *types.Message, score int64, reason string) {
+	if ctx.BanManager == nil || score == 0 || ctx.Dice.IsMaster(msg.Sender.UserID) {
+		return
+	}
+
+	place := msg.GroupID
+	if !types.IsGroupScene(msg.MessageType) {
+		place = "私聊"
+	}
+	item, rankChanged, err := ctx.BanManager.AddScore(msg.Sender.UserID, score, place, reason)
+	if err != nil || item == nil || !rankChanged || item.Rank != ban.BanRankBanned {
+		return
+	}
+
+	event := fmt.Sprintf("<%s>(%s)因%s怒气值增加%d，已被拉黑", msg.Sender.Nickname, msg.Sender.UserID, reason, score)
+	VarSetValueStr(ctx, "$t黑名单事件", event)
+	ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:黑名单触发_当事人"))
+}
@@ -5,7 +5,8 @@ import (
 )
 
 func ReplyToSenderRaw(ctx *types.MsgContext, msg *types.Message, text string, flag string) {
-	inGroup := msg.MessageType == "group"
+	// 频道消息同样按群聊回复，GroupID 指向子频道
+	inGroup := types.IsGroupScene(msg.MessageType)
 	if inGroup {
 		ReplyGroupRaw(ctx, msg, text, flag)
 	} else {
@@ -22,13 +23,17 @@ func ReplyToSender(ctx *types.MsgContext, msg *types.Message, text string) {
 // }
 
 func ReplyRaw(ctx *types.MsgContext, msg *types.Message, text string, flag string, messageType string) {
+	// 已超时被放弃的指令不再回复
+	if ctx.Context().Err() != nil {
+		return
+	}
 	var sendToGroupId string
 	if messageType == "group" {
 		sendToGroupId = msg.GroupID
 	}
 
-	ctx.Dice.SendReply(&types.MsgToReply{
-		AdapterId: msg.Platform,
+	reply := &types.MsgToReply{
+		AdapterId: ctx.AdapterId,
 		CommandId: ctx.CommandId,
 		Sender: types.MsgSenderInfo{
 			Platform: msg.Platform,
@@ -46,8 +51,12 @@ func ReplyRaw(ctx *types.MsgContext, msg *types.Message, text string, flag strin
 		MessageType: messageType,
 		Segments:    types.MessageSegments{&types.TextElement{Content: text}},
 
+		CommandInfo:       ctx.CommandInfo,
 		CommandFormatInfo: ctx.CommandFormatInfo,
-	})
+	}
+	if err := ctx.Dice.SendReply(reply); err == nil && ctx.ReplyRecorder != nil {
+		ctx.ReplyRecorder(reply)
+	}
 }
 
 func ReplyGroupRaw(ctx *types.MsgContext, msg *types.Message, text string, flag string) {
@@ -0,0 +1,27 @@
+package exts
+
+import (
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+// PrivilegeDeniedText 按所需权限等级给出对应的无权限提示
+func PrivilegeDeniedText(ctx *types.MsgContext, required int) string {
+	switch {
+	case required <= types.PrivilegeLevelInviter:
+		return DiceFormatTmpl(ctx, "核心:提示_无权限_非master/管理/邀请者")
+	case required <= types.PrivilegeLevelGroupOwner:
+		return DiceFormatTmpl(ctx, "核心:提示_无权限_非master/管理")
+	default:
+		return DiceFormatTmpl(ctx, "核心:提示_无权限")
+	}
+}
+
+// CheckPrivilege 检查发送者的权限等级，不足时回复与 CmdItemInfo.RequiredPrivilege 相同的提示
+// 用于只有部分子指令需要权限的场景，整条指令都需要权限时请使用 RequiredPrivilege
+func CheckPrivilege(ctx *types.MsgContext, msg *types.Message, required int) bool {
+	if ctx.PrivilegeLevel >= required {
+		return true
+	}
+	ReplyToSender(ctx, msg, PrivilegeDeniedText(ctx, required))
+	return false
+}
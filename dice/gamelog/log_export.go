@@ -0,0 +1,106 @@
+package gamelog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 导出格式
+const (
+	FormatHTML     = "html"
+	FormatMarkdown = "md"
+	FormatJSON     = "json"
+	FormatText     = "txt"
+)
+
+// Formats 支持的导出格式，第一项为默认格式
+var Formats = []string{FormatHTML, FormatMarkdown, FormatJSON, FormatText}
+
+// IsFormat 是否为支持的导出格式
+func IsFormat(format string) bool {
+	return slices.Contains(Formats, format)
+}
+
+// htmlColors 导出HTML时按用户分配的文字颜色
+var htmlColors = []string{
+	"#c0392b", "#2471a3", "#1e8449", "#7d3c98", "#b9770e",
+	"#117a65", "#a93226", "#2e4053", "#d35400", "#6c3483",
+}
+
+const diceColor = "#7f8c8d"
+
+// SortItems 按时间排序，时间相同时指令排在其回复之前，其余保持原顺序
+// 指令消息在执行完毕后才写入，因此存储中可能位于自己的回复之后
+func SortItems(items []*LogItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.Time != b.Time {
+			return a.Time < b.Time
+		}
+		return a.CommandID != 0 && a.CommandID == b.CommandID && !a.IsDice && b.IsDice
+	})
+}
+
+// Render 将日志渲染为指定格式，记录会先按时间排序
+func Render(format string, name string, items []*LogItem) ([]byte, error) {
+	items = append([]*LogItem(nil), items...)
+	SortItems(items)
+
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(struct {
+			Name  string     `json:"name"`
+			Items []*LogItem `json:"items"`
+		}{name, items}, "", "  ")
+	case FormatText:
+		var buf bytes.Buffer
+		for _, item := range items {
+			fmt.Fprintf(&buf, "%s(%s) %s\n%s\n\n", item.Nickname, item.UserID, formatTime(item.Time), item.Message)
+		}
+		return buf.Bytes(), nil
+	case FormatMarkdown:
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "# %s\n\n", name)
+		for _, item := range items {
+			fmt.Fprintf(&buf, "**%s** `%s`\n\n", item.Nickname, formatTime(item.Time))
+			for _, line := range strings.Split(item.Message, "\n") {
+				fmt.Fprintf(&buf, "> %s\n", line)
+			}
+			buf.WriteString("\n")
+		}
+		return buf.Bytes(), nil
+	case FormatHTML:
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n</head>\n<body>\n<h1>%s</h1>\n",
+			html.EscapeString(name), html.EscapeString(name))
+		for _, item := range items {
+			color := diceColor
+			if !item.IsDice {
+				color = userColor(item.UserID)
+			}
+			text := strings.ReplaceAll(html.EscapeString(item.Message), "\n", "<br>")
+			fmt.Fprintf(&buf, "<p style=\"color:%s\"><b>%s</b> <small>%s</small><br>%s</p>\n",
+				color, html.EscapeString(item.Nickname), formatTime(item.Time), text)
+		}
+		buf.WriteString("</body>\n</html>\n")
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported log format: %s", format)
+}
+
+func userColor(userID string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(userID))
+	return htmlColors[h.Sum32()%uint32(len(htmlColors))]
+}
+
+func formatTime(ms int64) string {
+	return time.UnixMilli(ms).Format("2006-01-02 15:04:05")
+}
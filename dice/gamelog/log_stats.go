@@ -0,0 +1,246 @@
+package gamelog
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 检定结果等级，与 coc7 扩展的 successRank 一致
+const (
+	rankFumble   = -2
+	rankCritical = 4
+)
+
+// SkillStat 单项技能的检定统计
+type SkillStat struct {
+	Name        string  `json:"name"`
+	Checks      int     `json:"checks"`
+	Successes   int     `json:"successes"`
+	SuccessRate float64 `json:"successRate"` // 0~1
+}
+
+// PlayerStat 单个角色的检定统计，角色以指令信息中的 pcName 区分
+type PlayerStat struct {
+	Name              string       `json:"name"`
+	Checks            int          `json:"checks"` // 检定次数，含理智检定
+	Successes         int          `json:"successes"`
+	SuccessRate       float64      `json:"successRate"` // 0~1
+	CriticalSuccesses int          `json:"criticalSuccesses"`
+	Fumbles           int          `json:"fumbles"`
+	SanChecks         int          `json:"sanChecks"`
+	SanLost           int64        `json:"sanLost"`
+	D100Count         int          `json:"d100Count"` // 参与平均值计算的 d100 出目数
+	AvgD100           float64      `json:"avgD100"`
+	Skills            []*SkillStat `json:"skills"` // 按检定次数从多到少排列
+}
+
+// SessionStats 一份日志的检定统计
+type SessionStats struct {
+	Name    string        `json:"name"`
+	Checks  int           `json:"checks"`
+	Players []*PlayerStat `json:"players"` // 按检定次数从多到少排列
+}
+
+// Player 按角色名查找统计
+func (s *SessionStats) Player(name string) *PlayerStat {
+	for _, p := range s.Players {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+type playerAcc struct {
+	stat    *PlayerStat
+	d100Sum int64
+	skills  map[string]*SkillStat
+}
+
+func (a *playerAcc) check(skill string, rank int64) {
+	a.stat.Checks++
+	s := a.skills[skill]
+	if s == nil {
+		s = &SkillStat{Name: skill}
+		a.skills[skill] = s
+	}
+	s.Checks++
+	if rank > 0 {
+		a.stat.Successes++
+		s.Successes++
+	}
+	switch rank {
+	case rankCritical:
+		a.stat.CriticalSuccesses++
+	case rankFumble:
+		a.stat.Fumbles++
+	}
+}
+
+func (a *playerAcc) d100(outcome int64) {
+	a.stat.D100Count++
+	a.d100Sum += outcome
+}
+
+// Stat 汇总日志中骰子回复附带的指令信息
+// 目前统计 coc7 的 ra/rc 检定与 sc 理智检定，暗骰不计入；同一指令的多条回复只计一次
+func Stat(name string, items []*LogItem) *SessionStats {
+	items = append([]*LogItem(nil), items...)
+	SortItems(items)
+
+	players := map[string]*playerAcc{}
+	getPlayer := func(name string) *playerAcc {
+		a := players[name]
+		if a == nil {
+			a = &playerAcc{stat: &PlayerStat{Name: name}, skills: map[string]*SkillStat{}}
+			players[name] = a
+		}
+		return a
+	}
+
+	seen := map[int64]bool{}
+	for _, item := range items {
+		info := item.CommandInfo
+		if !item.IsDice || info == nil {
+			continue
+		}
+		if item.CommandID != 0 {
+			if seen[item.CommandID] {
+				continue
+			}
+			seen[item.CommandID] = true
+		}
+		if hide, _ := info["hide"].(bool); hide {
+			continue
+		}
+		if rule, _ := info["rule"].(string); rule != "coc7" {
+			continue
+		}
+		pcName, _ := info["pcName"].(string)
+		if pcName == "" {
+			pcName = item.UserID
+		}
+
+		switch cmd, _ := info["cmd"].(string); cmd {
+		case "ra":
+			for _, sub := range infoItems(info) {
+				outcome, ok := toInt64(sub["outcome"])
+				if !ok {
+					continue
+				}
+				rank, _ := toInt64(sub["rank"])
+				expr1, _ := sub["expr1"].(string)
+				expr2, _ := sub["expr2"].(string)
+				p := getPlayer(pcName)
+				p.check(skillName(expr2), rank)
+				if isD100Expr(expr1) {
+					p.d100(outcome)
+				}
+			}
+		case "sc":
+			for _, sub := range infoItems(info) {
+				outcome, ok := toInt64(sub["outcome"])
+				if !ok {
+					continue
+				}
+				rank, _ := toInt64(sub["rank"])
+				p := getPlayer(pcName)
+				p.check("理智", rank)
+				p.d100(outcome)
+				p.stat.SanChecks++
+				sanOld, _ := toInt64(sub["sanOld"])
+				sanNew, _ := toInt64(sub["sanNew"])
+				if sanOld > sanNew {
+					p.stat.SanLost += sanOld - sanNew
+				}
+			}
+		}
+	}
+
+	ret := &SessionStats{Name: name, Players: make([]*PlayerStat, 0, len(players))}
+	for _, a := range players {
+		p := a.stat
+		p.SuccessRate = rate(p.Successes, p.Checks)
+		if p.D100Count > 0 {
+			p.AvgD100 = float64(a.d100Sum) / float64(p.D100Count)
+		}
+		for _, s := range a.skills {
+			s.SuccessRate = rate(s.Successes, s.Checks)
+			p.Skills = append(p.Skills, s)
+		}
+		sort.Slice(p.Skills, func(i, j int) bool {
+			if p.Skills[i].Checks != p.Skills[j].Checks {
+				return p.Skills[i].Checks > p.Skills[j].Checks
+			}
+			return p.Skills[i].Name < p.Skills[j].Name
+		})
+		ret.Checks += p.Checks
+		ret.Players = append(ret.Players, p)
+	}
+	sort.Slice(ret.Players, func(i, j int) bool {
+		if ret.Players[i].Checks != ret.Players[j].Checks {
+			return ret.Players[i].Checks > ret.Players[j].Checks
+		}
+		return ret.Players[i].Name < ret.Players[j].Name
+	})
+	return ret
+}
+
+func rate(n int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// infoItems 读取指令信息中的 items，兼容内存中的原始类型与 JSON 反序列化后的类型
+func infoItems(info map[string]any) []map[string]any {
+	var ret []map[string]any
+	switch items := info["items"].(type) {
+	case []any:
+		for _, v := range items {
+			if m, ok := v.(map[string]any); ok {
+				ret = append(ret, m)
+			}
+		}
+	case []map[string]any:
+		ret = items
+	}
+	return ret
+}
+
+func toInt64(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+// isD100Expr 检定所用的骰子是否为 d100，奖励骰与惩罚骰同样计入
+func isD100Expr(expr string) bool {
+	expr = strings.ToLower(strings.TrimSpace(expr))
+	return expr == "d100" || strings.HasPrefix(expr, "b") || strings.HasPrefix(expr, "p")
+}
+
+// skillName 从属性表达式中取出技能名，如 "侦查50" 取 "侦查"，"困难侦查" 取 "侦查"
+func skillName(expr string) string {
+	name := strings.TrimSpace(expr)
+	for _, prefix := range []string{"常规", "困难", "极难", "大成功", "常規", "困難", "極難"} {
+		if rest, ok := strings.CutPrefix(name, prefix); ok && rest != "" {
+			name = rest
+			break
+		}
+	}
+	if trimmed := strings.TrimRightFunc(name, unicode.IsDigit); trimmed != "" {
+		name = trimmed
+	}
+	return name
+}
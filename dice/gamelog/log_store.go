@@ -0,0 +1,178 @@
+package gamelog
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrLogNotFound = errors.New("log not found")
+	ErrLogExists   = errors.New("log already exists")
+)
+
+// LogItem 日志中的一条记录，可以是群内消息或骰子回复
+type LogItem struct {
+	Nickname  string `json:"nickname"`
+	UserID    string `json:"userId"`
+	Time      int64  `json:"time"` // 毫秒时间戳，导出时按此排序
+	Message   string `json:"message"`
+	IsDice    bool   `json:"isDice"`    // 是否为骰子的回复
+	CommandID int64  `json:"commandId"` // 对应的指令编号，非指令消息为0
+
+	CommandInfo map[string]any `json:"commandInfo,omitempty"` // 骰子回复附带的指令信息，用于统计
+}
+
+func (i *LogItem) clone() *LogItem {
+	c := *i
+	return &c
+}
+
+// LogInfo 日志概要
+type LogInfo struct {
+	GroupID   string `json:"groupId"`
+	Name      string `json:"name"`
+	Size      int    `json:"size"`      // 记录条数
+	CreatedAt int64  `json:"createdAt"` // 秒级时间戳
+	UpdatedAt int64  `json:"updatedAt"` // 秒级时间戳
+}
+
+// LogStore 定义了日志数据访问层的接口，日志以群号+日志名标识
+type LogStore interface {
+	// 新建日志，已存在时返回 ErrLogExists
+	Create(groupID string, name string) error
+	// 追加记录，日志不存在时返回 ErrLogNotFound
+	Append(groupID string, name string, item *LogItem) error
+	// 读取全部记录，日志不存在时返回 ErrLogNotFound
+	Items(groupID string, name string) ([]*LogItem, error)
+	// 列出群内全部日志
+	List(groupID string) ([]*LogInfo, error)
+	// 删除日志，返回是否存在
+	Delete(groupID string, name string) (bool, error)
+}
+
+type memoryLog struct {
+	info  LogInfo
+	items []*LogItem
+}
+
+// MemoryLogStore 基于内存的LogStore实现
+type MemoryLogStore struct {
+	mu   sync.RWMutex
+	logs map[string]map[string]*memoryLog
+}
+
+// NewMemoryLogStore 创建新的内存LogStore实例
+func NewMemoryLogStore() *MemoryLogStore {
+	return &MemoryLogStore{
+		logs: make(map[string]map[string]*memoryLog),
+	}
+}
+
+// Create 新建日志
+func (m *MemoryLogStore) Create(groupID string, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group := m.logs[groupID]
+	if group == nil {
+		group = make(map[string]*memoryLog)
+		m.logs[groupID] = group
+	}
+	if _, ok := group[name]; ok {
+		return ErrLogExists
+	}
+	now := time.Now().Unix()
+	group[name] = &memoryLog{info: LogInfo{GroupID: groupID, Name: name, CreatedAt: now, UpdatedAt: now}}
+	return nil
+}
+
+// Append 追加记录
+func (m *MemoryLogStore) Append(groupID string, name string, item *LogItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.logs[groupID][name]
+	if !ok {
+		return ErrLogNotFound
+	}
+	l.items = append(l.items, item.clone())
+	l.info.Size = len(l.items)
+	l.info.UpdatedAt = time.Now().Unix()
+	return nil
+}
+
+// Items 读取全部记录
+func (m *MemoryLogStore) Items(groupID string, name string) ([]*LogItem, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	l, ok := m.logs[groupID][name]
+	if !ok {
+		return nil, ErrLogNotFound
+	}
+	items := make([]*LogItem, 0, len(l.items))
+	for _, item := range l.items {
+		items = append(items, item.clone())
+	}
+	return items, nil
+}
+
+// List 列出群内全部日志，按创建时间排序
+func (m *MemoryLogStore) List(groupID string) ([]*LogInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	infos := make([]*LogInfo, 0, len(m.logs[groupID]))
+	for _, l := range m.logs[groupID] {
+		info := l.info
+		infos = append(infos, &info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].CreatedAt != infos[j].CreatedAt {
+			return infos[i].CreatedAt < infos[j].CreatedAt
+		}
+		return infos[i].Name < infos[j].Name
+	})
+	return infos, nil
+}
+
+// Delete 删除日志
+func (m *MemoryLogStore) Delete(groupID string, name string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.logs[groupID][name]; !ok {
+		return false, nil
+	}
+	delete(m.logs[groupID], name)
+	return true, nil
+}
+
+// LogManager 日志管理器，存储可在运行中替换
+type LogManager struct {
+	mu    sync.RWMutex
+	store LogStore
+}
+
+func NewLogManager() *LogManager {
+	return &LogManager{store: NewMemoryLogStore()}
+}
+
+// SetStore 替换日志存储，为 nil 时恢复为内存存储
+func (m *LogManager) SetStore(store LogStore) {
+	if store == nil {
+		store = NewMemoryLogStore()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store = store
+}
+
+// Store 当前的日志存储
+func (m *LogManager) Store() LogStore {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.store
+}
@@ -0,0 +1,280 @@
+package dice
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sealdice/smallseal/adapters"
+	"github.com/sealdice/smallseal/dice/gamelog"
+)
+
+// fileAdapter 记录上传的文件
+type fileAdapter struct {
+	adapters.PlatformAdapter
+	files []string
+}
+
+func (a *fileAdapter) MsgSendToGroup(*adapters.MessageSendRequest) (bool, error) {
+	return true, nil
+}
+
+func (a *fileAdapter) MsgSendToPerson(*adapters.MessageSendRequest) (bool, error) {
+	return true, nil
+}
+
+func (a *fileAdapter) MsgSendFileToGroup(req *adapters.MessageSendFileRequest) (bool, error) {
+	a.files = append(a.files, req.TargetId.(string)+" "+req.FilePath)
+	return true, nil
+}
+
+func newLogDice(t *testing.T) (*Dice, *fileAdapter, func(role string, content string) string) {
+	cfg := DefaultConfig()
+	cfg.LogExportDir = t.TempDir()
+	d := newTestDice(t, &cfg)
+
+	// 发送文件需要完整的适配器
+	adapter := &fileAdapter{}
+	require.NoError(t, d.RegisterAdapter("qq", adapter))
+	d.adapterID = "qq"
+	send := func(role string, content string) string {
+		return d.send(groupMessage("QQ:1", "木落", role, content))
+	}
+	return d.Dice, adapter, send
+}
+
+func TestLogRecordAndExport(t *testing.T) {
+	as := assert.New(t)
+	d, adapter, send := newLogDice(t)
+
+	as.Equal("本群还没有日志", send("", ".log list"))
+	send("", "不会被记录")
+	as.Equal("新日志 团1 已开始记录", send("", ".log new 团1"))
+	send("", "大家好")
+	rollReply := send("", ".r 1d1")
+	as.Contains(rollReply, "1")
+
+	as.Contains(send("", ".log off"), "日志 团1 已暂停")
+	send("", "暂停时的消息")
+	as.Equal("日志 团1 继续记录", send("", ".log on"))
+
+	items, err := d.logManager.Store().Items("QQ-Group:1", "团1")
+	require.NoError(t, err)
+	gamelog.SortItems(items)
+	var texts []string
+	for _, item := range items {
+		texts = append(texts, item.Message)
+	}
+	// 暂停期间的消息与暂停、继续的指令不会被记录
+	as.Equal([]string{"新日志 团1 已开始记录", "大家好", ".r 1d1", rollReply, "日志 团1 继续记录"}, texts)
+	as.True(items[0].IsDice)
+	as.Equal(int64(0), items[1].CommandID)
+	as.Equal("QQ:1", items[2].UserID)
+	as.NotZero(items[2].CommandID)
+	as.False(items[2].IsDice)
+	as.True(items[3].IsDice)
+	as.Equal(items[2].CommandID, items[3].CommandID, "reply shares the command id")
+
+	reply := send("", ".log end json")
+	as.Contains(reply, "记录结束，日志 团1 共")
+	as.Contains(reply, "日志文件已发送")
+	require.Len(t, adapter.files, 1)
+	path := filepath.Join(d.Config.LogExportDir, "QQ-Group_1_团1.json")
+	as.Equal("QQ-Group:1 "+path, adapter.files[0])
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var exported struct {
+		Name  string             `json:"name"`
+		Items []*gamelog.LogItem `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(data, &exported))
+	as.Equal("团1", exported.Name)
+	as.Equal("大家好", exported.Items[1].Message)
+	as.Equal(rollReply, exported.Items[3].Message)
+	as.NotEmpty(exported.Items[3].Nickname, "dice replies use the dice name")
+
+	// 结束后不再记录
+	group, ok := d.GroupInfoManager.Load("QQ-Group:1")
+	require.True(t, ok)
+	as.False(group.LogOn)
+	as.Empty(group.LogCurName)
+	send("", "结束后的消息")
+	after, err := d.logManager.Store().Items("QQ-Group:1", "团1")
+	require.NoError(t, err)
+	as.Len(after, len(exported.Items))
+
+	as.Contains(send("", ".log list"), "团1")
+	as.Contains(send("", ".log get 团1 md"), "日志文件已发送")
+	md, err := os.ReadFile(filepath.Join(d.Config.LogExportDir, "QQ-Group_1_团1.md"))
+	require.NoError(t, err)
+	as.Contains(string(md), "> 大家好")
+	as.Contains(send("", ".log get 团1 doc"), ".log get <日志名>")
+}
+
+func TestLogCommandErrors(t *testing.T) {
+	as := assert.New(t)
+	d, _, send := newLogDice(t)
+
+	as.Equal("当前没有日志，请使用 .log new 新建", send("", ".log end"))
+	as.Equal("当前没有日志，请使用 .log new 新建", send("", ".log on"))
+	as.Equal("未找到日志 nope", send("", ".log on nope"))
+	send("", ".log new a")
+	as.Contains(send("", ".log new b"), "日志 a 正在记录中")
+	send("", ".log off")
+	as.Contains(send("", ".log new a"), "日志 a 已存在")
+	as.Contains(send("", ".log list"), "a 3条")
+	as.Contains(send("", ".log list"), "[已暂停]")
+
+	as.Equal("你不是管理员或master", send("", ".log del a"))
+	as.Equal("已删除日志 a", send("admin", ".log del a"))
+	as.Equal("未找到日志 a", send("admin", ".log del a"))
+	group, ok := d.GroupInfoManager.Load("QQ-Group:1")
+	require.True(t, ok)
+	as.Empty(group.LogCurName)
+}
+
+func TestLogSendFileUnsupported(t *testing.T) {
+	as := assert.New(t)
+	cfg := DefaultConfig()
+	cfg.LogExportDir = t.TempDir()
+	d := newTestDice(t, &cfg)
+
+	var replies []string
+	for _, content := range []string{".log new x", "hello", ".log end txt"} {
+		msg := groupMessage("QQ:1", "user", "", content)
+		msg.GroupID = "QQ-Group:2"
+		d.exec(msg)
+		replies = append(replies, d.texts()...)
+	}
+	require.Len(t, replies, 2)
+	path := filepath.Join(cfg.LogExportDir, "QQ-Group_2_x.txt")
+	as.Contains(replies[1], ErrSendFileUnsupported.Error())
+	as.Contains(replies[1], path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	as.Contains(string(data), "user(QQ:1)")
+	as.Contains(string(data), "hello")
+}
+
+func TestLogStatsFromCommands(t *testing.T) {
+	as := assert.New(t)
+	d, _, send := newLogDice(t)
+
+	as.Equal("本群还没有日志，请使用 .log new 新建", send("", ".stat session"))
+	send("", ".log new 团1")
+	as.Equal("日志 团1 中还没有检定记录", send("", ".stat session"))
+	send("", ".ra 侦查50")
+	send("", ".ra 困难侦查50")
+	send("", ".sc 0/0")
+	send("", ".r d100")
+
+	stats, err := d.LogStats("QQ-Group:1", "团1")
+	require.NoError(t, err)
+	require.Len(t, stats.Players, 1)
+	p := stats.Players[0]
+	as.Equal("木落", p.Name)
+	as.Equal(3, p.Checks)
+	as.Equal(1, p.SanChecks)
+	as.Equal(int64(0), p.SanLost)
+	as.Equal(3, p.D100Count)
+	require.Len(t, p.Skills, 2)
+	as.Equal("侦查", p.Skills[0].Name)
+	as.Equal(2, p.Skills[0].Checks)
+	as.Equal("理智", p.Skills[1].Name)
+
+	reply := send("", ".stat session")
+	as.Contains(reply, "日志 团1 检定统计:")
+	as.Contains(reply, "木落 | 3 |")
+	as.Contains(reply, "技能成功率:\n木落: 侦查 ")
+
+	// 结束后默认统计最近的日志
+	send("", ".log end")
+	as.Contains(send("", ".stat session"), "日志 团1 检定统计:")
+	as.Equal("未找到日志 nope", send("", ".stat session nope"))
+	_, err = d.LogStats("QQ-Group:1", "nope")
+	as.ErrorIs(err, gamelog.ErrLogNotFound)
+}
+
+func TestLogStatsAggregate(t *testing.T) {
+	as := assert.New(t)
+	d, _, send := newLogDice(t)
+
+	store := d.logManager.Store()
+	require.NoError(t, store.Create("QQ-Group:1", "s"))
+	check := func(id int64, pc string, expr2 string, outcome int64, rank int) {
+		require.NoError(t, store.Append("QQ-Group:1", "s", &gamelog.LogItem{
+			IsDice:    true,
+			CommandID: id,
+			Time:      id,
+			CommandInfo: map[string]any{
+				"cmd": "ra", "rule": "coc7", "pcName": pc,
+				"items": []any{map[string]any{"expr1": "D100", "expr2": expr2, "outcome": outcome, "rank": rank}},
+			},
+		}))
+	}
+	check(1, "甲", "侦查60", 1, 4)
+	check(2, "甲", "侦查60", 70, -1)
+	check(3, "甲", "聆听", 100, -2)
+	check(3, "甲", "聆听", 100, -2) // 同一指令的重复回复
+	check(4, "乙", "力量", 20, 1)
+	require.NoError(t, store.Append("QQ-Group:1", "s", &gamelog.LogItem{
+		IsDice: true, CommandID: 5, Time: 5,
+		CommandInfo: map[string]any{"cmd": "ra", "rule": "coc7", "pcName": "乙", "hide": true,
+			"items": []any{map[string]any{"expr1": "D100", "expr2": "力量", "outcome": 90, "rank": -1}}},
+	}))
+	// 持久化存储读回的 JSON 数值为 float64
+	require.NoError(t, store.Append("QQ-Group:1", "s", &gamelog.LogItem{
+		IsDice: true, CommandID: 6, Time: 6,
+		CommandInfo: map[string]any{"cmd": "sc", "rule": "coc7", "pcName": "乙",
+			"items": []any{map[string]any{"outcome": float64(60), "rank": float64(-1), "sanOld": float64(50), "sanNew": float64(45)}}},
+	}))
+
+	stats, err := d.LogStats("QQ-Group:1", "s")
+	require.NoError(t, err)
+	as.Equal(5, stats.Checks)
+	a, b := stats.Player("甲"), stats.Player("乙")
+	require.NotNil(t, a)
+	require.NotNil(t, b)
+	as.Equal(3, a.Checks)
+	as.Equal(1, a.CriticalSuccesses)
+	as.Equal(1, a.Fumbles)
+	as.InDelta(1.0/3, a.SuccessRate, 1e-9)
+	as.InDelta(57.0, a.AvgD100, 1e-9)
+	as.Equal(2, b.Checks, "hidden checks are skipped")
+	as.Equal(int64(5), b.SanLost)
+	as.InDelta(0.5, b.SuccessRate, 1e-9)
+
+	reply := send("", ".stat session s")
+	as.Contains(reply, "甲 | 3 | 33% | 1 | 1 | 0 | 57.0")
+	as.Contains(reply, "乙 | 2 | 50% | 0 | 0 | 5 | 40.0")
+	as.Contains(reply, "甲: 侦查 1/2, 聆听 0/1")
+}
+
+func TestLogStopsWhenLogIsGone(t *testing.T) {
+	as := assert.New(t)
+	d, _, send := newLogDice(t)
+
+	as.Equal("新日志 团1 已开始记录", send("", ".log new 团1"))
+	// 模拟重启后内存存储中的日志丢失
+	_, err := d.logManager.Store().Delete("QQ-Group:1", "团1")
+	require.NoError(t, err)
+
+	as.Equal("日志 团1 已不存在，停止记录", send("", "大家好"))
+	group, ok := d.GroupInfoManager.Load("QQ-Group:1")
+	require.True(t, ok)
+	as.False(group.LogOn)
+	as.Empty(group.LogCurName)
+	as.Empty(send("", "之后的消息"))
+
+	as.Equal("新日志 团2 已开始记录", send("", ".log new 团2"))
+	send("", "继续")
+	items, err := d.logManager.Store().Items("QQ-Group:1", "团2")
+	require.NoError(t, err)
+	as.Len(items, 2)
+}
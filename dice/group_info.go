@@ -15,6 +15,16 @@ type GroupInfoManager interface {
 	Delete(groupId string)
 }
 
+// GroupInfoFlusher 可选接口，带缓存的群组信息管理器实现后，Dice.Close 时会调用 Flush 落盘
+type GroupInfoFlusher interface {
+	Flush() error
+}
+
+// GroupInfoRanger 可选接口，实现后可遍历全部群组信息，自动退群依赖此接口
+type GroupInfoRanger interface {
+	Range(fn func(groupId string, groupInfo *types.GroupInfo) bool)
+}
+
 // DefaultGroupInfoManager 默认的群组信息管理器实现
 type DefaultGroupInfoManager struct {
 	groupMap *utils.SyncMap[string, *types.GroupInfo]
@@ -41,3 +51,8 @@ func (m *DefaultGroupInfoManager) Store(groupId string, groupInfo *types.GroupIn
 func (m *DefaultGroupInfoManager) Delete(groupId string) {
 	m.groupMap.Delete(groupId)
 }
+
+// Range 遍历全部群组信息，fn 返回 false 时停止
+func (m *DefaultGroupInfoManager) Range(fn func(groupId string, groupInfo *types.GroupInfo) bool) {
+	m.groupMap.Range(fn)
+}
@@ -0,0 +1,394 @@
+package helpdoc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/lascape/sat"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPackage 帮助目录根下的文件归入的文档包
+const DefaultPackage = "default"
+
+// HelpItem 一条帮助文档
+type HelpItem struct {
+	ID      int    // 编号，按加载顺序从1开始，重新加载后会变化
+	Package string // 所属文档包，即帮助目录下的一级目录名
+	Title   string
+	Content string
+	From    string // 来源文件，相对于帮助目录
+
+	normTitle   string
+	normContent string
+}
+
+// HelpPackage 文档包信息
+type HelpPackage struct {
+	Name      string
+	ItemCount int
+}
+
+// SearchResult 搜索结果，Score 越高越匹配
+type SearchResult struct {
+	Item  *HelpItem
+	Score int
+}
+
+// helpDocFile JSON/YAML 格式的帮助文件
+type helpDocFile struct {
+	Mod     string            `json:"mod"     yaml:"mod"`
+	Author  string            `json:"author"  yaml:"author"`
+	Brief   string            `json:"brief"   yaml:"brief"`
+	Helpdoc map[string]string `json:"helpdoc" yaml:"helpdoc"`
+}
+
+// HelpManager 帮助文档管理器，文档整体替换，读到的条目不会再被修改
+type HelpManager struct {
+	mu    sync.RWMutex
+	dir   string
+	items []*HelpItem
+}
+
+func NewHelpManager() *HelpManager {
+	return &HelpManager{}
+}
+
+var (
+	chsDict     sat.Dicter
+	chsDictOnce sync.Once
+)
+
+// Normalize 统一大小写与繁简，并去掉空白，用于匹配
+func Normalize(s string) string {
+	chsDictOnce.Do(func() {
+		chsDict = sat.DefaultDict()
+	})
+	s = strings.ToLower(chsDict.Read(s))
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// LoadDir 读取帮助目录，替换当前全部文档，失败时保持原文档
+// 一级目录为文档包，支持 .md .json .yaml .yml 文件，目录不存在时视为没有文档
+func (m *HelpManager) LoadDir(dir string) error {
+	items, err := loadDir(dir)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dir = dir
+	m.items = items
+	return nil
+}
+
+// Reload 重新读取当前帮助目录
+func (m *HelpManager) Reload() error {
+	dir := m.Dir()
+	if dir == "" {
+		return fmt.Errorf("未设置帮助文档目录")
+	}
+	return m.LoadDir(dir)
+}
+
+// Dir 当前的帮助目录
+func (m *HelpManager) Dir() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.dir
+}
+
+// AddItem 追加一条文档，重新加载目录后会被清除
+func (m *HelpManager) AddItem(pkg string, title string, content string) *HelpItem {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item := newHelpItem(pkg, title, content, "")
+	item.ID = len(m.items) + 1
+	m.items = append(slices.Clip(m.items), item)
+	return item
+}
+
+// Packages 列出全部文档包，按名称排序
+func (m *HelpManager) Packages() []HelpPackage {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	counts := map[string]int{}
+	for _, item := range m.items {
+		counts[item.Package]++
+	}
+	ret := make([]HelpPackage, 0, len(counts))
+	for name, n := range counts {
+		ret = append(ret, HelpPackage{Name: name, ItemCount: n})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+// HasPackage 文档包是否存在
+func (m *HelpManager) HasPackage(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.ContainsFunc(m.items, func(item *HelpItem) bool { return item.Package == name })
+}
+
+// Get 按编号获取文档，packages 为空时不限制文档包，否则不在其中的文档视为不存在
+func (m *HelpManager) Get(id int, packages []string) (*HelpItem, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if id < 1 || id > len(m.items) {
+		return nil, false
+	}
+	item := m.items[id-1]
+	if !packageEnabled(item.Package, packages) {
+		return nil, false
+	}
+	return item, true
+}
+
+// Lookup 按标题精确查找(忽略大小写、繁简与空白)
+// packages 为空时查找全部文档包，preferred 包中的结果排在前面
+func (m *HelpManager) Lookup(title string, packages []string, preferred string) []*HelpItem {
+	key := Normalize(title)
+	if key == "" {
+		return nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var ret []*HelpItem
+	for _, item := range m.items {
+		if item.normTitle == key && packageEnabled(item.Package, packages) {
+			ret = append(ret, item)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Package == preferred && ret[j].Package != preferred
+	})
+	return ret
+}
+
+// Search 模糊搜索标题与全文，关键词以空白分隔，返回至多 limit 条结果
+// packages 为空时搜索全部文档包，preferred 包中的结果略微优先
+func (m *HelpManager) Search(keyword string, packages []string, preferred string, limit int) []SearchResult {
+	var tokens []string
+	for _, field := range strings.Fields(keyword) {
+		if t := Normalize(field); t != "" {
+			tokens = append(tokens, t)
+		}
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	m.mu.RLock()
+	var ret []SearchResult
+	for _, item := range m.items {
+		if !packageEnabled(item.Package, packages) {
+			continue
+		}
+		score := 0
+		for _, t := range tokens {
+			score += scoreToken(item, t)
+		}
+		if score == 0 {
+			continue
+		}
+		if preferred != "" && item.Package == preferred {
+			score += 5
+		}
+		ret = append(ret, SearchResult{Item: item, Score: score})
+	}
+	m.mu.RUnlock()
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		a, b := ret[i], ret[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return len(a.Item.normTitle) < len(b.Item.normTitle)
+	})
+	if limit > 0 && len(ret) > limit {
+		ret = ret[:limit]
+	}
+	return ret
+}
+
+func packageEnabled(pkg string, packages []string) bool {
+	return len(packages) == 0 || slices.Contains(packages, pkg)
+}
+
+// scoreToken 单个关键词的得分: 标题相等 > 标题包含 > 标题按序含有全部字符 > 正文包含 > 正文字符二元组部分命中
+func scoreToken(item *HelpItem, t string) int {
+	score := 0
+	switch {
+	case item.normTitle == t:
+		score += 100
+	case strings.Contains(item.normTitle, t):
+		score += 60
+	case isSubsequence(t, item.normTitle):
+		score += 30
+	}
+	if n := strings.Count(item.normContent, t); n > 0 {
+		score += 20 + 2*min(n, 5)
+	} else if score == 0 {
+		if r := bigramHitRate(t, item.normTitle+item.normContent); r >= 0.5 {
+			score += int(20 * r)
+		}
+	}
+	return score
+}
+
+func isSubsequence(sub string, s string) bool {
+	rs := []rune(sub)
+	i := 0
+	for _, r := range s {
+		if i < len(rs) && rs[i] == r {
+			i++
+		}
+	}
+	return i == len(rs)
+}
+
+// bigramHitRate 关键词中相邻两字在文本中出现的比例，用于容忍错字
+func bigramHitRate(t string, text string) float64 {
+	rs := []rune(t)
+	if len(rs) < 3 {
+		return 0
+	}
+	hit := 0
+	for i := 0; i+1 < len(rs); i++ {
+		if strings.Contains(text, string(rs[i:i+2])) {
+			hit++
+		}
+	}
+	return float64(hit) / float64(len(rs)-1)
+}
+
+func newHelpItem(pkg string, title string, content string, from string) *HelpItem {
+	title = strings.TrimSpace(title)
+	content = strings.TrimSpace(content)
+	return &HelpItem{
+		Package:     pkg,
+		Title:       title,
+		Content:     content,
+		From:        from,
+		normTitle:   Normalize(title),
+		normContent: Normalize(content),
+	}
+}
+
+func loadDir(dir string) ([]*HelpItem, error) {
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	var items []*HelpItem
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		pkg := DefaultPackage
+		if parts := strings.Split(filepath.ToSlash(rel), "/"); len(parts) > 1 {
+			pkg = parts[0]
+		}
+		loaded, err := loadFile(path, pkg, filepath.ToSlash(rel))
+		if err != nil {
+			return fmt.Errorf("helpdoc %s: %w", rel, err)
+		}
+		items = append(items, loaded...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, item := range items {
+		item.ID = i + 1
+	}
+	return items, nil
+}
+
+func loadFile(path string, pkg string, rel string) ([]*HelpItem, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".md", ".json", ".yaml", ".yml":
+	default:
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if ext == ".md" {
+		return parseMarkdown(data, pkg, rel), nil
+	}
+
+	var doc helpDocFile
+	if ext == ".json" {
+		err = json.Unmarshal(data, &doc)
+	} else {
+		err = yaml.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	titles := make([]string, 0, len(doc.Helpdoc))
+	for title := range doc.Helpdoc {
+		titles = append(titles, title)
+	}
+	sort.Strings(titles)
+	items := make([]*HelpItem, 0, len(titles))
+	for _, title := range titles {
+		items = append(items, newHelpItem(pkg, title, doc.Helpdoc[title], rel))
+	}
+	return items, nil
+}
+
+// parseMarkdown 每个标题行(任意级别的 #)开始一条文档，没有标题时整个文件为一条，标题取文件名
+func parseMarkdown(data []byte, pkg string, rel string) []*HelpItem {
+	var items []*HelpItem
+	title := ""
+	var body []string
+	flush := func() {
+		if title != "" {
+			items = append(items, newHelpItem(pkg, title, strings.Join(body, "\n"), rel))
+		}
+		body = nil
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(line, "#") {
+			heading := strings.TrimSpace(strings.TrimLeft(line, "#"))
+			if heading != "" {
+				flush()
+				title = heading
+				continue
+			}
+		}
+		body = append(body, line)
+	}
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(rel), filepath.Ext(rel))
+	}
+	flush()
+	return items
+}
@@ -0,0 +1,111 @@
+package dice
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeHelpdocFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return dir
+}
+
+func newHelpdocDice(t *testing.T) (*Dice, func(role string, content string) string) {
+	dir := writeHelpdocFiles(t, map[string]string{
+		"coc7/rules.md":  "# 理智检定\n使用 .sc 进行理智检定，失败时损失更多理智\n\n## 幸运\n幸运值可用于孤注一掷",
+		"dnd5e/doc.yaml": "mod: dnd5e\nhelpdoc:\n  先攻: 使用 .ri 加入先攻列表\n  幸运: 半身人的种族特性\n",
+		"faq.json":       `{"mod": "faq", "helpdoc": {"骰主": "联系骰主请使用 .send"}}`,
+		"coc7/notes.txt": "ignored",
+	})
+
+	cfg := DefaultConfig()
+	cfg.HelpdocDir = dir
+	d := newTestDice(t, &cfg)
+	send := func(role string, content string) string {
+		return d.send(groupMessage("QQ:1", "user", role, content))
+	}
+	return d.Dice, send
+}
+
+func TestHelpdocLoadAndLookup(t *testing.T) {
+	as := assert.New(t)
+	d, send := newHelpdocDice(t)
+
+	var names []string
+	for _, pkg := range d.HelpManager().Packages() {
+		names = append(names, pkg.Name)
+	}
+	as.Equal([]string{"coc7", "default", "dnd5e"}, names)
+
+	reply := send("", ".help 理智檢定")
+	as.Contains(reply, "理智检定(coc7)")
+	as.Contains(reply, "使用 .sc 进行理智检定")
+	as.NotContains(reply, "幸运值")
+
+	reply = send("", ".help 幸运")
+	as.Contains(reply, "幸运(coc7)\n幸运值可用于孤注一掷")
+	as.Contains(reply, "同名词条:")
+	as.Contains(reply, "幸运(dnd5e)")
+
+	as.Contains(send("", ".help ra"), "检定", "commands are still preferred")
+	as.Contains(send("", ".help 不存在"), "未找到指令或词条")
+}
+
+func TestHelpdocFind(t *testing.T) {
+	as := assert.New(t)
+	_, send := newHelpdocDice(t)
+
+	reply := send("", ".find 先攻")
+	as.Contains(reply, "先攻(dnd5e)\n使用 .ri 加入先攻列表")
+
+	// 全文搜索，标题命中的排在前面
+	reply = send("", ".find 理智")
+	as.Contains(reply, "理智检定(coc7)")
+	as.NotContains(reply, "其他可能的结果")
+
+	reply = send("", ".find 孤注")
+	as.Contains(reply, "幸运(coc7)")
+
+	// 按序含有全部字符的模糊匹配
+	as.Contains(send("", ".find 理检"), "理智检定(coc7)")
+
+	reply = send("", ".find 幸运")
+	as.Contains(reply, "其他可能的结果:")
+	as.Contains(send("", ".find #1"), "理智检定")
+	as.Contains(send("", ".find #99"), "未找到编号为 99 的词条")
+	as.Contains(send("", ".find 完全无关"), "未找到与 完全无关 相关的词条")
+}
+
+func TestHelpdocGroupPackages(t *testing.T) {
+	as := assert.New(t)
+	d, send := newHelpdocDice(t)
+
+	as.Equal("你不是管理员或master", send("", ".helpdoc use dnd5e"))
+	as.Equal("本群启用的帮助文档包: dnd5e, default", send("admin", ".helpdoc use dnd5e default"))
+	as.Equal("未找到: nope", send("admin", ".helpdoc use nope"))
+
+	group, ok := d.GroupInfoManager.Load("QQ-Group:1")
+	require.True(t, ok)
+	as.Equal([]string{"dnd5e", "default"}, group.HelpPackages)
+
+	as.Contains(send("", ".help 幸运"), "幸运(dnd5e)\n半身人的种族特性")
+	as.Contains(send("", ".help 理智检定"), "未找到指令或词条")
+	as.Equal("未找到编号为 1 的词条", send("", ".find #1"), "find by id respects the group packages")
+	as.Contains(send("", ".helpdoc"), "coc7 2条\ndefault 1条 [启用]\ndnd5e 2条 [启用]")
+
+	as.Equal("本群已启用全部帮助文档包", send("admin", ".helpdoc all"))
+	as.Equal("本群优先的帮助文档包: dnd5e", send("admin", ".helpdoc default dnd5e"))
+	as.Equal("dnd5e", group.DefaultHelpGroup)
+	as.Regexp(`^\[#\d+\] 幸运\(dnd5e\)`, send("", ".help 幸运"), "preferred package comes first")
+	as.Contains(send("", ".helpdoc list"), "dnd5e 2条 [启用] [优先]")
+	as.Equal("你没有权限这样做", send("admin", ".helpdoc reload"), "reload is master only")
+}
@@ -78,6 +78,14 @@ func (r *hookRegistry[T]) unregister(handle types.HookHandle) bool {
 	return true
 }
 
+func (r *hookRegistry[T]) clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.items = nil
+	r.index = nil
+}
+
 func (r *hookRegistry[T]) snapshot() []hookEntry[T] {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
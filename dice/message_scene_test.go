@@ -0,0 +1,63 @@
+package dice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+func TestChannelMessageScene(t *testing.T) {
+	as := assert.New(t)
+	d := newTestDice(t, nil)
+
+	var scenes []string
+	d.RegisterExtension(&types.ExtInfo{
+		Name:       "scene",
+		AutoActive: true,
+		OnNotCommandReceived: func(ctx *types.MsgContext, msg *types.Message) {
+			scenes = append(scenes, ctx.MessageScene)
+		},
+	})
+
+	send := func(channelID string, content string) *ExecuteResult {
+		return d.exec(&types.Message{
+			Platform:    "QQ",
+			MessageType: "guild",
+			GuildID:     "100",
+			ChannelID:   channelID,
+			Sender:      types.SenderBase{UserID: "QQ-CH:1", Nickname: "user"},
+			Segments:    types.MessageSegments{&types.TextElement{Content: content}},
+		})
+	}
+
+	result := send("7", ".r d1")
+	as.Equal("core", result.ExtName)
+	if as.Len(d.replies, 1) {
+		as.Equal("group", d.replies[0].MessageType)
+		as.Equal("QQ-CH-Group:100-7", d.replies[0].SendTo.GroupId)
+	}
+	as.Equal([]string{"guild"}, scenes)
+
+	group, ok := d.GroupInfoManager.Load("QQ-CH-Group:100-7")
+	require.True(t, ok)
+	as.Equal("100", group.GuildID)
+	as.Equal("7", group.ChannelID)
+	as.Equal("test", group.AdapterId)
+
+	send("8", ".r d1")
+	_, ok = d.GroupInfoManager.Load("QQ-CH-Group:100-8")
+	as.True(ok, "each channel has its own group info")
+
+	guildID, channelID, ok := types.ParseChannelGroupID("QQ-CH-Group:100-7")
+	as.True(ok)
+	as.Equal("100", guildID)
+	as.Equal("7", channelID)
+	_, _, ok = types.ParseChannelGroupID("QQ-Group:100")
+	as.False(ok)
+
+	d.exec(&types.Message{MessageType: "guild", GuildID: "100", Segments: types.MessageSegments{&types.TextElement{Content: ".r d1"}}})
+	as.Empty(d.replies, "channel message without channel id is dropped")
+}
@@ -0,0 +1,285 @@
+package dice
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+var (
+	ErrPipelineRunning    = errors.New("pipeline already running")
+	ErrPipelineNotStarted = errors.New("pipeline not started")
+	ErrPipelineClosed     = errors.New("pipeline closed")
+	ErrPipelineQueueFull  = errors.New("pipeline queue full")
+)
+
+const defaultPipelineQueueSize = 256
+
+// PipelineConfig 异步执行管线设置
+type PipelineConfig struct {
+	Workers   int // worker 数量，<=0 时使用 CPU 核数
+	QueueSize int // 每个 worker 的队列长度，<=0 时为 256
+
+	// OnResult 每条消息执行完毕后回调，在 worker 中调用
+	OnResult func(adapterId string, msg *types.Message, result *ExecuteResult)
+}
+
+// PipelineStats 管线的运行统计，用于观察积压情况
+type PipelineStats struct {
+	Workers   int
+	Capacity  int    // 全部队列的总容量
+	Queued    int64  // 排队中的消息数
+	Running   int64  // 执行中的消息数
+	Submitted uint64 // 累计入队
+	Completed uint64 // 累计完成
+	Rejected  uint64 // 因队列已满或已关闭被拒绝
+}
+
+type pipelineTask struct {
+	adapterId string
+	msg       *types.Message
+	fn        func() // 不为空时在 msg 所属的队列中执行 fn，而不是处理消息
+}
+
+// pipeline 按群分片的 worker 池，同一群(或私聊用户)的消息总是进入同一队列，从而保持顺序
+type pipeline struct {
+	cfg    PipelineConfig
+	queues []chan pipelineTask
+
+	mu       sync.RWMutex
+	closed   bool
+	stopping chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	queued    atomic.Int64
+	running   atomic.Int64
+	submitted atomic.Uint64
+	completed atomic.Uint64
+	rejected  atomic.Uint64
+}
+
+// StartPipeline 启动异步执行管线，之后可用 Submit/TrySubmit 投递消息
+func (d *Dice) StartPipeline(cfg PipelineConfig) error {
+	d.closeMu.RLock()
+	closed := d.closed
+	d.closeMu.RUnlock()
+	if closed {
+		return ErrDiceClosed
+	}
+
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.NumCPU()
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultPipelineQueueSize
+	}
+
+	p := &pipeline{
+		cfg:      cfg,
+		queues:   make([]chan pipelineTask, cfg.Workers),
+		stopping: make(chan struct{}),
+	}
+	if !d.pipeline.CompareAndSwap(nil, p) {
+		return ErrPipelineRunning
+	}
+
+	for i := range p.queues {
+		p.queues[i] = make(chan pipelineTask, cfg.QueueSize)
+		p.wg.Add(1)
+		go p.work(d, p.queues[i])
+	}
+	return nil
+}
+
+// Submit 投递消息，队列已满时阻塞等待，直到有空位、ctx 结束或管线关闭
+func (d *Dice) Submit(ctx context.Context, adapterId string, msg *types.Message) error {
+	p := d.pipeline.Load()
+	if p == nil {
+		return ErrPipelineNotStarted
+	}
+	return p.submit(ctx, pipelineTask{adapterId: adapterId, msg: msg}, true)
+}
+
+// TrySubmit 投递消息，队列已满时立即返回 ErrPipelineQueueFull
+func (d *Dice) TrySubmit(adapterId string, msg *types.Message) error {
+	p := d.pipeline.Load()
+	if p == nil {
+		return ErrPipelineNotStarted
+	}
+	return p.submit(context.Background(), pipelineTask{adapterId: adapterId, msg: msg}, false)
+}
+
+// runInShard 在 msg 所属的队列中执行 fn 并等待其结束，使后台任务与该群的消息处理串行地修改群组数据
+// 管线未启动时直接执行
+func (d *Dice) runInShard(msg *types.Message, fn func()) error {
+	p := d.pipeline.Load()
+	if p == nil {
+		fn()
+		return nil
+	}
+	done := make(chan struct{})
+	task := pipelineTask{msg: msg, fn: func() {
+		defer close(done)
+		fn()
+	}}
+	if err := p.submit(context.Background(), task, true); err != nil {
+		return err
+	}
+	<-done
+	return nil
+}
+
+// StopPipeline 停止接收新消息，并等待已入队的消息执行完毕
+// ctx 结束时不再等待并返回 ctx.Err()，剩余消息仍会在后台执行完
+func (d *Dice) StopPipeline(ctx context.Context) error {
+	p := d.pipeline.Load()
+	if p == nil {
+		return nil
+	}
+	p.close()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.pipeline.CompareAndSwap(p, nil)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PipelineStats 获取管线统计，未启动时返回零值
+func (d *Dice) PipelineStats() PipelineStats {
+	p := d.pipeline.Load()
+	if p == nil {
+		return PipelineStats{}
+	}
+	return PipelineStats{
+		Workers:   len(p.queues),
+		Capacity:  len(p.queues) * p.cfg.QueueSize,
+		Queued:    p.queued.Load(),
+		Running:   p.running.Load(),
+		Submitted: p.submitted.Load(),
+		Completed: p.completed.Load(),
+		Rejected:  p.rejected.Load(),
+	}
+}
+
+func (p *pipeline) submit(ctx context.Context, task pipelineTask, wait bool) error {
+	if task.msg == nil {
+		return nil
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		p.rejected.Add(1)
+		return ErrPipelineClosed
+	}
+
+	queue := p.queues[pipelineShard(task.msg, len(p.queues))]
+
+	// 先计数再入队，避免 worker 取出时计数出现负数
+	p.queued.Add(1)
+	if !wait {
+		select {
+		case queue <- task:
+			p.submitted.Add(1)
+			return nil
+		default:
+			p.queued.Add(-1)
+			p.rejected.Add(1)
+			return ErrPipelineQueueFull
+		}
+	}
+
+	select {
+	case queue <- task:
+		p.submitted.Add(1)
+		return nil
+	case <-p.stopping:
+		p.queued.Add(-1)
+		p.rejected.Add(1)
+		return ErrPipelineClosed
+	case <-ctx.Done():
+		p.queued.Add(-1)
+		p.rejected.Add(1)
+		return ctx.Err()
+	}
+}
+
+func (p *pipeline) close() {
+	// 先唤醒阻塞中的 Submit，再关闭队列
+	p.stopOnce.Do(func() {
+		close(p.stopping)
+	})
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	for _, q := range p.queues {
+		close(q)
+	}
+}
+
+func (p *pipeline) work(d *Dice, queue <-chan pipelineTask) {
+	defer p.wg.Done()
+	for task := range queue {
+		p.queued.Add(-1)
+		p.running.Add(1)
+		if task.fn != nil {
+			if err := safeCall("pipeline:task", task.fn); err != nil {
+				d.dispatchExecuteError(task.adapterId, task.msg, err)
+			}
+		} else {
+			p.execute(d, task)
+		}
+		p.running.Add(-1)
+		p.completed.Add(1)
+	}
+}
+
+func (p *pipeline) execute(d *Dice, task pipelineTask) {
+	var result *ExecuteResult
+	if err := safeCall("pipeline", func() {
+		result = d.execute(task.adapterId, task.msg)
+	}); err != nil {
+		d.dispatchExecuteError(task.adapterId, task.msg, err)
+		result = &ExecuteResult{Err: err}
+	}
+	if p.cfg.OnResult != nil {
+		if err := safeCall("pipeline:OnResult", func() {
+			p.cfg.OnResult(task.adapterId, task.msg, result)
+		}); err != nil {
+			d.dispatchExecuteError(task.adapterId, task.msg, err)
+		}
+	}
+}
+
+// pipelineShard 按群号分片，私聊按用户分片，未填群号的频道消息按子频道分片
+func pipelineShard(msg *types.Message, n int) int {
+	key := "group:" + msg.GroupID
+	switch {
+	case !types.IsGroupScene(msg.MessageType):
+		key = "private:" + msg.Sender.UserID
+	case msg.GroupID == "":
+		key = "channel:" + msg.GuildID + "-" + msg.ChannelID
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
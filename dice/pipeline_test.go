@@ -0,0 +1,174 @@
+package dice
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+func newPipelineTestDice(t *testing.T, solve func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs)) *testDice {
+	d := newTestDice(t, nil)
+	d.RegisterExtension(&types.ExtInfo{
+		Name:       "pipe",
+		AutoActive: true,
+		CmdMap: types.CmdMapCls{
+			"seq": &types.CmdItemInfo{
+				Name: "seq",
+				Solve: func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs) types.CmdExecuteResult {
+					solve(ctx, msg, cmdArgs)
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				},
+			},
+		},
+	})
+	return d
+}
+
+func pipelineTestMessage(groupID string, content string) *types.Message {
+	msg := groupMessage("QQ:1", "tester", "", content)
+	msg.GroupID = groupID
+	return msg
+}
+
+func TestPipelineKeepsPerGroupOrder(t *testing.T) {
+	as := assert.New(t)
+
+	var mu sync.Mutex
+	seen := map[string][]string{}
+	d := newPipelineTestDice(t, func(ctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs) {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		seen[msg.GroupID] = append(seen[msg.GroupID], cmdArgs.GetArgN(1))
+		mu.Unlock()
+	})
+
+	var results sync.WaitGroup
+	require.NoError(t, d.StartPipeline(PipelineConfig{
+		Workers:   4,
+		QueueSize: 8,
+		OnResult: func(_ string, _ *types.Message, result *ExecuteResult) {
+			as.True(result.Solved)
+			results.Done()
+		},
+	}))
+	as.ErrorIs(d.StartPipeline(PipelineConfig{}), ErrPipelineRunning)
+
+	groups := []string{"QQ-Group:1", "QQ-Group:2", "QQ-Group:3"}
+	expected := []string{}
+	for i := 0; i < 20; i++ {
+		expected = append(expected, fmt.Sprint(i))
+		for _, g := range groups {
+			results.Add(1)
+			as.NoError(d.Submit(context.Background(), "test", pipelineTestMessage(g, fmt.Sprintf(".seq %d", i))))
+		}
+	}
+
+	as.NoError(d.StopPipeline(context.Background()))
+	results.Wait()
+	for _, g := range groups {
+		as.Equal(expected, seen[g], g)
+	}
+
+	as.Equal(PipelineStats{}, d.PipelineStats())
+	as.ErrorIs(d.TrySubmit("test", pipelineTestMessage(groups[0], ".seq")), ErrPipelineNotStarted)
+}
+
+func TestPipelineBackpressure(t *testing.T) {
+	as := assert.New(t)
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	d := newPipelineTestDice(t, func(*types.MsgContext, *types.Message, *types.CmdArgs) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+	})
+	d.Config.ExecuteTimeout = 0
+	require.NoError(t, d.StartPipeline(PipelineConfig{Workers: 1, QueueSize: 1}))
+
+	as.NoError(d.TrySubmit("test", pipelineTestMessage("QQ-Group:1", ".seq")))
+	<-started
+	as.NoError(d.TrySubmit("test", pipelineTestMessage("QQ-Group:1", ".seq")))
+	as.ErrorIs(d.TrySubmit("test", pipelineTestMessage("QQ-Group:2", ".seq")), ErrPipelineQueueFull)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	as.ErrorIs(d.Submit(ctx, "test", pipelineTestMessage("QQ-Group:2", ".seq")), context.DeadlineExceeded)
+
+	stats := d.PipelineStats()
+	as.Equal(1, stats.Workers)
+	as.Equal(int64(1), stats.Queued)
+	as.Equal(int64(1), stats.Running)
+	as.Equal(uint64(2), stats.Submitted)
+	as.Equal(uint64(2), stats.Rejected)
+
+	// 排空超时后消息仍在执行
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer stopCancel()
+	as.ErrorIs(d.StopPipeline(stopCtx), context.DeadlineExceeded)
+	as.ErrorIs(d.TrySubmit("test", pipelineTestMessage("QQ-Group:1", ".seq")), ErrPipelineClosed)
+
+	close(release)
+	as.NoError(d.StopPipeline(context.Background()))
+}
+
+func TestPipelineOrdersEventsWithMessages(t *testing.T) {
+	as := assert.New(t)
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) {
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+	}
+	release := make(chan struct{})
+	started := make(chan struct{})
+	d := newPipelineTestDice(t, func(*types.MsgContext, *types.Message, *types.CmdArgs) {
+		close(started)
+		<-release
+		record("seq")
+	})
+	d.RegisterExtension(&types.ExtInfo{
+		Name:       "member",
+		AutoActive: true,
+		OnGroupMemberJoined: func(*types.MsgContext, *types.Message) {
+			record("member")
+		},
+	})
+	require.NoError(t, d.StartPipeline(PipelineConfig{Workers: 4}))
+
+	as.NoError(d.TrySubmit("test", pipelineTestMessage("QQ-Group:1", ".seq")))
+	<-started
+	dispatched := make(chan struct{})
+	go func() {
+		d.DispatchEvent("test", &types.AdapterEvent{
+			Platform: "QQ",
+			PostType: "notice",
+			Type:     types.AdapterEventGroupIncrease,
+			GroupID:  "QQ-Group:1",
+			UserID:   "QQ:2",
+		})
+		close(dispatched)
+	}()
+
+	// 事件排在同群的指令之后
+	select {
+	case <-dispatched:
+		t.Fatal("event dispatched while the group is busy")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	<-dispatched
+	as.NoError(d.StopPipeline(context.Background()))
+	as.Equal([]string{"seq", "member"}, order)
+}
@@ -0,0 +1,88 @@
+package dice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sealdice/smallseal/adapters"
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+type tgAdapter struct {
+	adapters.PlatformAdapter
+	sent []*adapters.MessageSendRequest
+}
+
+func (a *tgAdapter) Platform() string { return "TG" }
+
+func (a *tgAdapter) MsgSendToGroup(request *adapters.MessageSendRequest) (bool, error) {
+	a.sent = append(a.sent, request)
+	return true, nil
+}
+
+func TestAdapterPlatformPrefix(t *testing.T) {
+	as := assert.New(t)
+	d := NewDice()
+	adapter := &tgAdapter{}
+	require.NoError(t, d.RegisterAdapter("tg", adapter))
+
+	send := func(segments ...types.IMessageElement) *ExecuteResult {
+		return d.Execute("tg", &types.Message{
+			Platform:    "TG",
+			MessageType: "group",
+			GroupID:     "TG-Group:1",
+			SelfID:      "TG:999",
+			Sender:      types.SenderBase{UserID: "TG:1", Nickname: "user"},
+			Segments:    segments,
+		})
+	}
+
+	result := send(&types.AtElement{Target: "999"}, &types.TextElement{Content: " .r d1"})
+	require.NotNil(t, result.CmdArgs)
+	as.Equal("r", result.CmdArgs.Command)
+	if as.Len(result.CmdArgs.At, 1) {
+		as.Equal("TG:999", result.CmdArgs.At[0].UserID)
+	}
+	as.True(result.CmdArgs.AmIBeMentioned)
+	as.True(result.CmdArgs.AmIBeMentionedFirst)
+	as.Len(adapter.sent, 1)
+
+	result = send(&types.TextElement{Content: ".r d1 "}, &types.AtElement{Target: "TG:123"})
+	require.NotNil(t, result.CmdArgs)
+	as.Equal("d1", result.CmdArgs.CleanArgs)
+	as.False(result.CmdArgs.AmIBeMentioned)
+	as.True(result.CmdArgs.SomeoneBeMentionedButNotMe)
+
+	// 仅注册了发送回调的适配器默认使用配置中的平台前缀
+	require.NoError(t, d.RegisterAdapterSender("console", func(*types.MsgToReply) {}))
+	result = d.Execute("console", &types.Message{
+		MessageType: "private",
+		Sender:      types.SenderBase{UserID: "QQ:1"},
+		Segments:    types.MessageSegments{&types.AtElement{Target: "5"}, &types.TextElement{Content: ".r d1"}},
+	})
+	require.NotNil(t, result.CmdArgs)
+	as.Equal("QQ:5", result.CmdArgs.At[0].UserID)
+
+	require.NoError(t, d.SetAdapterPlatform("console", "DISCORD"))
+	result = d.Execute("console", &types.Message{
+		MessageType: "private",
+		Sender:      types.SenderBase{UserID: "DISCORD:1"},
+		Segments:    types.MessageSegments{&types.AtElement{Target: "5"}, &types.TextElement{Content: ".r d1"}},
+	})
+	require.NotNil(t, result.CmdArgs)
+	as.Equal("DISCORD:5", result.CmdArgs.At[0].UserID)
+
+	as.ErrorIs(d.SetAdapterPlatform("missing", "TG"), ErrAdapterNotFound)
+	as.Error(d.SetAdapterPlatform("console", "TG:x"))
+
+	as.Equal("TG:123", d.attrsManager.UIDConvert("TG", "123"))
+	as.Equal("QQ:123", d.attrsManager.UIDConvert("TG", "QQ:123"))
+	// 读写属性时不补全前缀，已有的纯数字ID的数据不受影响
+	item, err := d.attrsManager.Load("TG-Group:1", "123")
+	require.NoError(t, err)
+	as.Equal("TG-Group:1-123", item.ID)
+	as.Equal("TG-Group:1", adapters.FormatDiceIDGroup("TG", "1"))
+	as.Equal("DISCORD-CH-Group:1-2", adapters.FormatDiceIDChGroup("DISCORD", "1", "2"))
+}
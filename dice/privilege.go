@@ -0,0 +1,40 @@
+package dice
+
+import (
+	"github.com/sealdice/smallseal/dice/ban"
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+// calcPrivilegeLevel 根据master列表、信任名单和群内角色计算发送者的权限等级
+func (d *Dice) calcPrivilegeLevel(mctx *types.MsgContext, msg *types.Message) int {
+	userID := msg.Sender.UserID
+	if d.IsMaster(userID) {
+		return types.PrivilegeLevelMaster
+	}
+
+	if d.banManager != nil {
+		switch d.banManager.RankOf(userID) {
+		case ban.BanRankTrusted:
+			return types.PrivilegeLevelTrusted
+		case ban.BanRankBanned:
+			return types.PrivilegeLevelBanned
+		}
+	}
+
+	// 私聊中没有其他人，视同群主
+	if msg.MessageType == "private" {
+		return types.PrivilegeLevelGroupOwner
+	}
+
+	switch msg.Sender.GroupRole {
+	case "owner":
+		return types.PrivilegeLevelGroupOwner
+	case "admin":
+		return types.PrivilegeLevelGroupAdmin
+	}
+
+	if mctx.Group != nil && mctx.Group.InviteUserID != "" && mctx.Group.InviteUserID == userID {
+		return types.PrivilegeLevelInviter
+	}
+	return types.PrivilegeLevelUser
+}
@@ -0,0 +1,70 @@
+package dice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sealdice/smallseal/dice/ban"
+)
+
+func TestPrivilegeLevelRestrictsAdminCommands(t *testing.T) {
+	as := assert.New(t)
+
+	d := newTestDice(t, nil)
+	d.MasterAdd("QQ:master")
+	_, err := d.BanManager().SetRank("QQ:trusted", "", ban.BanRankTrusted, "", "")
+	as.NoError(err)
+	send := func(userID string, role string, content string) []string {
+		d.exec(groupMessage(userID, userID, role, content))
+		return d.texts()
+	}
+
+	as.Equal([]string{"你不是管理员或master"}, send("QQ:user", "", ".set 20"))
+	as.Equal([]string{"你不是管理员或master"}, send("QQ:user", "", ".text abc"))
+	as.Equal([]string{"你不是管理员或master"}, send("QQ:user", "", ".ext off coc7"))
+	as.Equal([]string{"你不是管理员或master"}, send("QQ:user", "", ".bot off"))
+
+	group, ok := d.GroupInfoManager.Load("QQ-Group:1")
+	as.True(ok)
+	as.True(group.Active)
+	as.True(group.IsExtensionActive("coc7"))
+
+	send("QQ:admin", "admin", ".set 20")
+	as.Equal("d20", group.DiceSideExpr)
+	as.Equal([]string{"abc"}, send("QQ:trusted", "", ".text abc"))
+	send("QQ:master", "", ".ext off coc7")
+	as.False(group.IsExtensionActive("coc7"))
+	send("QQ:owner", "owner", ".bot off")
+	as.False(group.Active)
+}
+
+func TestBotOnAndTrustCommands(t *testing.T) {
+	as := assert.New(t)
+
+	d := newTestDice(t, nil)
+	d.MasterAdd("QQ:master")
+	send := func(userID string, role string, content string) []string {
+		d.exec(groupMessage(userID, userID, role, content))
+		return d.texts()
+	}
+
+	as.Equal([]string{"你没有权限这样做"}, send("QQ:user", "", ".ban trust QQ:trusted"))
+	as.Equal([]string{"已将 QQ:trusted 设为信任用户"}, send("QQ:master", "", ".ban trust QQ:trusted"))
+	as.Equal(ban.BanRankTrusted, d.BanManager().RankOf("QQ:trusted"))
+	as.Equal([]string{"信任名单:\nQQ:trusted"}, send("QQ:master", "", ".ban trust"))
+
+	send("QQ:admin", "admin", ".bot off")
+	group, ok := d.GroupInfoManager.Load("QQ-Group:1")
+	as.True(ok)
+	as.False(group.Active)
+
+	as.Equal([]string{"你不是管理员或master"}, send("QQ:user", "", ".bot on"))
+	as.False(group.Active, "ordinary members cannot turn the bot back on")
+
+	send("QQ:trusted", "", ".bot on")
+	as.True(group.Active, "trusted users rank above group admins")
+
+	send("QQ:master", "", ".ban rm QQ:trusted")
+	as.Equal(ban.BanRankNormal, d.BanManager().RankOf("QQ:trusted"))
+}
@@ -0,0 +1,99 @@
+package dice
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/sealdice/smallseal/dice/exts"
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+// RateLimitConfig 指令限速配置，个人与群组各自使用一个令牌桶
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	PersonalReplenishRate rate.Limit `yaml:"personalReplenishRate"` // 个人令牌补充速率(个/秒)
+	PersonalBurst         int        `yaml:"personalBurst"`         // 个人令牌桶容量
+	GroupReplenishRate    rate.Limit `yaml:"groupReplenishRate"`    // 群组令牌补充速率(个/秒)
+	GroupBurst            int        `yaml:"groupBurst"`            // 群组令牌桶容量
+}
+
+func defaultRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		Enabled:               false,
+		PersonalReplenishRate: rate.Every(3 * time.Second),
+		PersonalBurst:         3,
+		GroupReplenishRate:    rate.Every(3 * time.Second),
+		GroupBurst:            20,
+	}
+}
+
+// userRateLimit 单个用户的令牌桶，按用户ID保存在 Dice 上，跨群与私聊共用
+type userRateLimit struct {
+	mu      sync.Mutex
+	limiter *rate.Limiter
+	warned  bool
+}
+
+// checkRateLimit 检查当前指令是否超出限速，超出时首次回复警告，之后静默丢弃直到令牌恢复
+// 返回 true 表示放行
+func (d *Dice) checkRateLimit(mctx *types.MsgContext, msg *types.Message) bool {
+	cfg := d.currentConfig().RateLimit
+	if !cfg.Enabled || !mctx.IsCurGroupBotOn || d.IsMaster(msg.Sender.UserID) {
+		return true
+	}
+
+	if uid := msg.Sender.UserID; uid != "" {
+		user, _ := d.userRateLimits.LoadOrStore(uid, &userRateLimit{})
+		user.mu.Lock()
+		user.limiter = syncLimiter(user.limiter, cfg.PersonalReplenishRate, cfg.PersonalBurst)
+		allowed := user.limiter.Allow()
+		warn := !allowed && !user.warned
+		user.warned = !allowed
+		user.mu.Unlock()
+		if !allowed {
+			if warn {
+				exts.ReplyToSender(mctx, msg, exts.DiceFormatTmpl(mctx, "核心:刷屏_警告内容_个人"))
+				d.banAddScoreForRateLimit(mctx, msg)
+			}
+			return false
+		}
+	}
+
+	if group := mctx.Group; group != nil && types.IsGroupScene(msg.MessageType) {
+		group.RateLimiter = syncLimiter(group.RateLimiter, cfg.GroupReplenishRate, cfg.GroupBurst)
+		if !group.RateLimiter.Allow() {
+			if !group.RateLimitWarned {
+				group.RateLimitWarned = true
+				exts.ReplyToSender(mctx, msg, exts.DiceFormatTmpl(mctx, "核心:刷屏_警告内容_群组"))
+			}
+			return false
+		}
+		group.RateLimitWarned = false
+	}
+
+	return true
+}
+
+// syncLimiter 按当前配置创建令牌桶，已有的令牌桶在配置变化后同步更新速率与容量
+func syncLimiter(limiter *rate.Limiter, limit rate.Limit, burst int) *rate.Limiter {
+	if limiter == nil {
+		return rate.NewLimiter(limit, burst)
+	}
+	if limiter.Limit() != limit {
+		limiter.SetLimit(limit)
+	}
+	if limiter.Burst() != burst {
+		limiter.SetBurst(burst)
+	}
+	return limiter
+}
+
+func (d *Dice) banAddScoreForRateLimit(mctx *types.MsgContext, msg *types.Message) {
+	if d.banManager == nil {
+		return
+	}
+	exts.BanAddScoreForSender(mctx, msg, d.banManager.Config().ScoreRateLimit, "刷屏")
+}
@@ -0,0 +1,99 @@
+package dice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimitWarnsOnceThenDrops(t *testing.T) {
+	as := assert.New(t)
+
+	cfg := DefaultConfig()
+	cfg.RateLimit = RateLimitConfig{
+		Enabled:               true,
+		PersonalReplenishRate: rate.Every(time.Hour),
+		PersonalBurst:         2,
+		GroupReplenishRate:    rate.Inf,
+		GroupBurst:            1,
+	}
+	d := newTestDice(t, &cfg)
+
+	var replies []string
+	send := func(userID string, content string) {
+		d.exec(groupMessage(userID, userID, "", content))
+		replies = append(replies, d.texts()...)
+	}
+
+	for range 4 {
+		send("QQ:1", ".r d20")
+	}
+	as.Len(replies, 3, "two rolls and one warning expected")
+	as.Equal("警告：您的指令频率过高，请注意。", replies[2])
+
+	send("QQ:1", "just chatting")
+	as.Len(replies, 3, "non-command messages are not limited")
+
+	d.MasterAdd("QQ:2")
+	for range 4 {
+		send("QQ:2", ".r d20")
+	}
+	as.Len(replies, 7, "masters are exempt")
+}
+
+func TestRateLimitFollowsConfigReload(t *testing.T) {
+	as := assert.New(t)
+
+	cfg := DefaultConfig()
+	cfg.RateLimit = RateLimitConfig{
+		Enabled:               true,
+		PersonalReplenishRate: rate.Every(time.Hour),
+		PersonalBurst:         1,
+		GroupReplenishRate:    rate.Inf,
+		GroupBurst:            1,
+	}
+	d := newTestDice(t, &cfg)
+
+	send := func() string {
+		return d.send(groupMessage("QQ:1", "user", "", ".r d20"))
+	}
+
+	as.NotEmpty(send())
+	as.Equal("警告：您的指令频率过高，请注意。", send())
+	as.Empty(send(), "warned only once")
+
+	// 已创建的令牌桶同样按新配置生效
+	cfg.RateLimit.PersonalReplenishRate = rate.Inf
+	as.NoError(d.ApplyConfig(cfg))
+	as.Contains(send(), "d20")
+	as.Contains(send(), "d20")
+}
+
+func TestRateLimitSharedAcrossGroups(t *testing.T) {
+	as := assert.New(t)
+
+	cfg := DefaultConfig()
+	cfg.RateLimit = RateLimitConfig{
+		Enabled:               true,
+		PersonalReplenishRate: rate.Every(time.Hour),
+		PersonalBurst:         2,
+		GroupReplenishRate:    rate.Inf,
+		GroupBurst:            1,
+	}
+	d := newTestDice(t, &cfg)
+
+	// 个人令牌桶在不同群与私聊间共用
+	var replies []string
+	for _, groupID := range []string{"QQ-Group:1", "QQ-Group:2", ""} {
+		msg := groupMessage("QQ:1", "user", "", ".r d20")
+		msg.GroupID = groupID
+		if groupID == "" {
+			msg.MessageType = "private"
+		}
+		replies = append(replies, d.send(msg))
+	}
+	as.Len(replies, 3)
+	as.Equal("警告：您的指令频率过高，请注意。", replies[2])
+}
@@ -0,0 +1,188 @@
+package dice
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sealdice/smallseal/adapters"
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+// RequestPolicyMode 好友申请、入群邀请的处理方式
+type RequestPolicyMode string
+
+const (
+	RequestPolicyManual     RequestPolicyMode = "manual"     // 不处理，交由事件钩子或人工
+	RequestPolicyAccept     RequestPolicyMode = "accept"     // 全部同意
+	RequestPolicyMasterOnly RequestPolicyMode = "master"     // 仅同意骰主
+	RequestPolicyPassphrase RequestPolicyMode = "passphrase" // 骰主，或验证信息中的回答与暗号一致时同意
+	RequestPolicyReject     RequestPolicyMode = "reject"     // 除骰主外全部拒绝
+)
+
+// RequestPolicy 单类申请的处理策略
+// 除 manual 外，黑名单中的用户(入群邀请还包括被拉黑的群)总是被拒绝，骰主总是被同意
+type RequestPolicy struct {
+	Mode            RequestPolicyMode `yaml:"mode"`
+	Passphrase      string            `yaml:"passphrase"`      // passphrase 模式下的暗号
+	RejectUnmatched bool              `yaml:"rejectUnmatched"` // master/passphrase 模式下不满足条件时拒绝，否则不处理
+}
+
+// RequestPolicyConfig 好友申请与入群邀请各自的策略
+type RequestPolicyConfig struct {
+	Friend      RequestPolicy `yaml:"friend"`
+	GroupInvite RequestPolicy `yaml:"groupInvite"`
+}
+
+type requestDecision int
+
+const (
+	requestIgnore requestDecision = iota
+	requestApprove
+	requestReject
+)
+
+const (
+	requestRejectBanned    = "在黑名单中"
+	requestRejectPolicy    = "暂不接受申请"
+	requestRejectUnmatched = "验证信息不正确"
+)
+
+// decideRequest 按策略判断申请的处理方式，拒绝时一并返回理由
+func (d *Dice) decideRequest(policy RequestPolicy, evt *types.AdapterEvent) (requestDecision, string) {
+	if policy.Mode == "" || policy.Mode == RequestPolicyManual {
+		return requestIgnore, ""
+	}
+	if d.IsMaster(evt.UserID) {
+		return requestApprove, ""
+	}
+	if d.banManager != nil {
+		if d.banManager.IsBanned(evt.UserID) || (evt.GroupID != "" && d.banManager.IsBanned(evt.GroupID)) {
+			return requestReject, requestRejectBanned
+		}
+	}
+
+	matched := false
+	switch policy.Mode {
+	case RequestPolicyAccept:
+		return requestApprove, ""
+	case RequestPolicyReject:
+		return requestReject, requestRejectPolicy
+	case RequestPolicyPassphrase:
+		passphrase := strings.TrimSpace(policy.Passphrase)
+		matched = passphrase != "" && requestAnswer(rawString(evt.Raw, "comment")) == passphrase
+	}
+
+	if matched {
+		return requestApprove, ""
+	}
+	if policy.RejectUnmatched {
+		return requestReject, requestRejectUnmatched
+	}
+	return requestIgnore, ""
+}
+
+// requestAnswer 取出验证信息中的回答，QQ 的验证信息形如 "问题：xxx\n回答：yyy"
+func requestAnswer(comment string) string {
+	for _, sep := range []string{"回答：", "回答:"} {
+		if idx := strings.LastIndex(comment, sep); idx >= 0 {
+			return strings.TrimSpace(comment[idx+len(sep):])
+		}
+	}
+	return strings.TrimSpace(comment)
+}
+
+// handleRequestEvent 按 Config.RequestPolicy 处理好友申请与入群邀请
+// 事件钩子中断事件时不会执行到这里，可借此自行处理
+func (d *Dice) handleRequestEvent(adapterID string, evt *types.AdapterEvent) {
+	if evt.PostType != "request" {
+		return
+	}
+
+	cfg := d.currentConfig()
+	var policy RequestPolicy
+	isInvite := false
+	switch {
+	case evt.Type == types.AdapterEventFriendRequest || evt.Type == "friend":
+		policy = cfg.RequestPolicy.Friend
+	case evt.Type == types.AdapterEventGroupInvite || (evt.Type == "group" && evt.SubType == "invite"):
+		policy = cfg.RequestPolicy.GroupInvite
+		isInvite = true
+	default:
+		return
+	}
+
+	decision, reason := d.decideRequest(policy, evt)
+	if decision == requestIgnore {
+		return
+	}
+	approve := decision == requestApprove
+
+	flag := rawString(evt.Raw, "flag")
+	if flag == "" {
+		flag = rawString(evt.Raw, "request_id")
+	}
+
+	var err error
+	if adapter, ok := d.GetAdapter(adapterID); !ok {
+		err = fmt.Errorf("%w: %q", ErrAdapterNotFound, adapterID)
+	} else if isInvite {
+		_, err = adapter.GroupInviteSet(&adapters.GroupInviteSetRequest{
+			GroupID: evt.GroupID,
+			Flag:    flag,
+			Approve: approve,
+			Reason:  reason,
+		})
+	} else {
+		_, err = adapter.FriendRequestSet(&adapters.FriendRequestSetRequest{
+			Flag:    flag,
+			Approve: approve,
+			Reason:  reason,
+		})
+	}
+
+	if err == nil && approve && isInvite && evt.GroupID != "" {
+		d.recordGroupInvite(evt.GroupID, evt.UserID, cfg)
+	}
+	d.dispatchRequestSet(adapterID, evt, approve, reason, err)
+}
+
+// recordGroupInvite 记录邀请人与入群时间，邀请人据此获得邀请者权限
+func (d *Dice) recordGroupInvite(groupID string, inviter string, cfg Config) {
+	groupInfo, ok := d.GroupInfoManager.Load(groupID)
+	if !ok {
+		groupInfo = d.newGroupInfo(groupID, cfg)
+	}
+	now := time.Now().Unix()
+	groupInfo.InviteUserID = inviter
+	groupInfo.EnteredTime = now
+	groupInfo.UpdatedAtTime = now
+	d.GroupInfoManager.Store(groupID, groupInfo)
+}
+
+func (d *Dice) dispatchRequestSet(adapterID string, src *types.AdapterEvent, approve bool, reason string, err error) {
+	evt := &types.AdapterEvent{
+		Platform: src.Platform,
+		PostType: EventPostTypeInternal,
+		Type:     EventTypeRequestSet,
+		SubType:  src.Type,
+		Time:     time.Now().Unix(),
+		GroupID:  src.GroupID,
+		UserID:   src.UserID,
+		Raw: map[string]any{
+			"approve": approve,
+			"reason":  reason,
+		},
+	}
+	if err != nil {
+		evt.Raw["error"] = err.Error()
+	}
+	d.runEventHooks(adapterID, evt)
+}
+
+func rawString(raw map[string]any, key string) string {
+	if raw == nil {
+		return ""
+	}
+	return rawIDString(raw[key])
+}
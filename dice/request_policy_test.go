@@ -0,0 +1,155 @@
+package dice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sealdice/smallseal/adapters"
+	"github.com/sealdice/smallseal/dice/ban"
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+// requestAdapter 只实现申请处理的适配器，其余方法不会被调用
+type requestAdapter struct {
+	adapters.PlatformAdapter
+	friends []*adapters.FriendRequestSetRequest
+	invites []*adapters.GroupInviteSetRequest
+}
+
+func (a *requestAdapter) FriendRequestSet(req *adapters.FriendRequestSetRequest) (bool, error) {
+	a.friends = append(a.friends, req)
+	return true, nil
+}
+
+func (a *requestAdapter) GroupInviteSet(req *adapters.GroupInviteSetRequest) (bool, error) {
+	a.invites = append(a.invites, req)
+	return true, nil
+}
+
+func TestRequestPolicyFriend(t *testing.T) {
+	as := assert.New(t)
+
+	d := NewDice()
+	adapter := &requestAdapter{}
+	require.NoError(t, d.RegisterAdapter("test", adapter))
+	d.MasterAdd("QQ:master")
+	_, err := d.BanManager().SetRank("QQ:bad", "", ban.BanRankBanned, "", "")
+	require.NoError(t, err)
+
+	friend := func(userID string, comment string) *adapters.FriendRequestSetRequest {
+		adapter.friends = nil
+		d.DispatchEvent("test", &types.AdapterEvent{
+			Platform: "QQ",
+			PostType: "request",
+			Type:     "friend",
+			UserID:   userID,
+			Raw:      map[string]any{"flag": "f-" + userID, "comment": comment},
+		})
+		if len(adapter.friends) == 0 {
+			return nil
+		}
+		return adapter.friends[0]
+	}
+
+	as.Nil(friend("QQ:1", ""), "manual by default")
+
+	d.Config.RequestPolicy.Friend = RequestPolicy{Mode: RequestPolicyPassphrase, Passphrase: "海豹"}
+	if req := friend("QQ:1", "问题：暗号是？\n回答：海豹"); as.NotNil(req) {
+		as.True(req.Approve)
+		as.Equal("f-QQ:1", req.Flag)
+	}
+	as.Nil(friend("QQ:2", "问题：暗号是？\n回答：鲸鱼"), "unmatched requests are left alone")
+	if req := friend("QQ:master", ""); as.NotNil(req) {
+		as.True(req.Approve)
+	}
+	if req := friend("QQ:bad", "海豹"); as.NotNil(req) {
+		as.False(req.Approve)
+		as.Equal(requestRejectBanned, req.Reason)
+	}
+
+	d.Config.RequestPolicy.Friend.RejectUnmatched = true
+	if req := friend("QQ:2", "鲸鱼"); as.NotNil(req) {
+		as.False(req.Approve)
+	}
+
+	// 事件钩子中断后不再按策略处理
+	_, err = d.RegisterEventHook("manual", types.HookPriorityNormal, func(_ types.DiceLike, _ string, evt *types.AdapterEvent) types.HookResult {
+		if evt.PostType == "request" {
+			return types.HookResultAbort
+		}
+		return types.HookResultContinue
+	})
+	require.NoError(t, err)
+	as.Nil(friend("QQ:master", ""))
+}
+
+func TestRequestPolicyGroupInvite(t *testing.T) {
+	as := assert.New(t)
+
+	d := NewDice()
+	adapter := &requestAdapter{}
+	require.NoError(t, d.RegisterAdapter("test", adapter))
+	d.Config.RequestPolicy.GroupInvite = RequestPolicy{Mode: RequestPolicyAccept}
+
+	var handled []*types.AdapterEvent
+	_, err := d.RegisterEventHook("collect", types.HookPriorityNormal, func(_ types.DiceLike, _ string, evt *types.AdapterEvent) types.HookResult {
+		if evt.Type == EventTypeRequestSet {
+			handled = append(handled, evt)
+		}
+		return types.HookResultContinue
+	})
+	require.NoError(t, err)
+
+	d.DispatchEvent("test", &types.AdapterEvent{
+		Platform: "QQ",
+		PostType: "request",
+		Type:     types.AdapterEventGroupInvite,
+		GroupID:  "QQ-Group:100",
+		UserID:   "QQ:inviter",
+		Raw:      map[string]any{"request_id": "r1"},
+	})
+	require.Len(t, adapter.invites, 1)
+	as.True(adapter.invites[0].Approve)
+	as.Equal("r1", adapter.invites[0].Flag)
+	as.Equal("QQ-Group:100", adapter.invites[0].GroupID)
+
+	group, ok := d.GroupInfoManager.Load("QQ-Group:100")
+	require.True(t, ok)
+	as.Equal("QQ:inviter", group.InviteUserID)
+	as.NotZero(group.EnteredTime)
+
+	if as.Len(handled, 1) {
+		as.Equal(true, handled[0].Raw["approve"])
+		as.Nil(handled[0].Raw["error"])
+	}
+
+	// 被拉黑的群的邀请总是拒绝
+	_, err = d.BanManager().SetRank("QQ-Group:200", "", ban.BanRankBanned, "", "")
+	require.NoError(t, err)
+	d.DispatchEvent("test", &types.AdapterEvent{
+		PostType: "request",
+		Type:     "group",
+		SubType:  "invite",
+		GroupID:  "QQ-Group:200",
+		UserID:   "QQ:inviter",
+		Raw:      map[string]any{"flag": "f2"},
+	})
+	require.Len(t, adapter.invites, 2)
+	as.False(adapter.invites[1].Approve)
+	_, ok = d.GroupInfoManager.Load("QQ-Group:200")
+	as.False(ok)
+}
+
+func TestRequestPolicyValidate(t *testing.T) {
+	_, err := ParseConfig([]byte(`
+requestPolicy:
+  friend:
+    mode: passphrase
+  groupInvite:
+    mode: sometimes
+`))
+	assert.ErrorContains(t, err, "config requestPolicy.friend.passphrase")
+	assert.ErrorContains(t, err, "config requestPolicy.groupInvite.mode")
+}
@@ -0,0 +1,144 @@
+package dice
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/sealdice/smallseal/dice/exts"
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+// 内部事件，通过 EventHook 派发，PostType 固定为 EventPostTypeInternal
+const (
+	EventPostTypeInternal = "internal"
+	EventTypePanic        = "panic"         // 指令、扩展回调或钩子发生 panic
+	EventTypeTimeout      = "timeout"       // 指令或钩子执行超时
+	EventTypeConfigReload = "config_reload" // 配置文件热更新
+	EventTypeRequestSet   = "request_set"   // 按策略处理了好友申请或入群邀请
+	EventTypeAutoQuit     = "auto_quit"     // 不活跃群组的退群预告或退群
+	EventTypeSendFailed   = "send_failed"   // 回复投递失败，如找不到来源适配器
+)
+
+// ExecuteError 描述一次被隔离的执行异常
+type ExecuteError struct {
+	Source  string // 出错位置，如 solve:roll、hook:in:name
+	Value   any    // recover 得到的值
+	Stack   string
+	Timeout bool
+}
+
+func (e *ExecuteError) Error() string {
+	if e.Timeout {
+		return fmt.Sprintf("%s: execution timed out", e.Source)
+	}
+	return fmt.Sprintf("%s: panic: %v", e.Source, e.Value)
+}
+
+// safeCall 执行fn，并将其中的 panic 转换为 ExecuteError
+func safeCall(source string, fn func()) (err *ExecuteError) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = &ExecuteError{Source: source, Value: rec, Stack: string(debug.Stack())}
+		}
+	}()
+	fn()
+	return nil
+}
+
+// guardedCall 在隔离环境中执行fn，超过 Config.ExecuteTimeout 时取消传给fn的 context 并返回超时错误
+// 超时后仍会等待fn真正返回：fn与调用方共享群组、用户等数据，提前返回会让同一分片的后续消息与其并发修改。
+// 取消之后经由该 context 发出的回复会被丢弃，fn也应通过 context 尽快停止
+func (d *Dice) guardedCall(parent context.Context, source string, fn func(ctx context.Context)) *ExecuteError {
+	timeout := d.currentConfig().ExecuteTimeout
+	if timeout <= 0 {
+		return safeCall(source, func() {
+			fn(parent)
+		})
+	}
+
+	runCtx, cancel := context.WithCancel(parent)
+	defer cancel()
+	done := make(chan *ExecuteError, 1)
+	go func() {
+		done <- safeCall(source, func() {
+			fn(runCtx)
+		})
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		cancel()
+		<-done
+		return &ExecuteError{Source: source, Timeout: true}
+	}
+}
+
+// solveGuarded 使用 guardedCall 执行指令
+func (d *Dice) solveGuarded(mctx *types.MsgContext, msg *types.Message, cmdArgs *types.CmdArgs, cmd *types.CmdItemInfo) (types.CmdExecuteResult, *ExecuteError) {
+	var result types.CmdExecuteResult
+	err := d.guardedCtxCall(mctx, "solve:"+cmd.Name, func() {
+		result = cmd.Solve(mctx, msg, cmdArgs)
+	})
+	if err != nil {
+		return types.CmdExecuteResult{}, err
+	}
+	return result, nil
+}
+
+// guardedCtxCall 使用 guardedCall 执行fn，期间 mctx 的 context 为 guardedCall 给出的 context，
+// 超时后经由 mctx 发出的回复因此被丢弃
+func (d *Dice) guardedCtxCall(mctx *types.MsgContext, source string, fn func()) *ExecuteError {
+	parent := mctx.Context()
+	defer mctx.SetContext(parent)
+	return d.guardedCall(parent, source, func(ctx context.Context) {
+		mctx.SetContext(ctx)
+		fn()
+	})
+}
+
+// replyExecuteError 告知用户指令执行失败，并派发错误事件
+func (d *Dice) replyExecuteError(adapterID string, mctx *types.MsgContext, msg *types.Message, err *ExecuteError) {
+	key := "核心:骰子执行异常"
+	if err.Timeout {
+		key = "核心:骰子执行超时"
+	}
+	// 回复过程本身出错时不再继续上报，避免循环
+	if replyErr := safeCall("reply:error", func() {
+		exts.ReplyToSender(mctx, msg, exts.DiceFormatTmpl(mctx, key))
+	}); replyErr != nil {
+		d.dispatchExecuteError(adapterID, msg, replyErr)
+	}
+	d.dispatchExecuteError(adapterID, msg, err)
+}
+
+// dispatchExecuteError 将执行异常作为内部事件交给 EventHook 处理
+func (d *Dice) dispatchExecuteError(adapterID string, msg *types.Message, err *ExecuteError) {
+	evt := &types.AdapterEvent{
+		PostType: EventPostTypeInternal,
+		Type:     EventTypePanic,
+		SubType:  err.Source,
+		Time:     time.Now().Unix(),
+		Raw: map[string]any{
+			"error": err.Error(),
+			"stack": err.Stack,
+		},
+	}
+	if err.Timeout {
+		evt.Type = EventTypeTimeout
+	}
+	if msg != nil {
+		evt.Platform = msg.Platform
+		evt.GroupID = msg.GroupID
+		evt.GuildID = msg.GuildID
+		evt.ChannelID = msg.ChannelID
+		evt.UserID = msg.Sender.UserID
+		evt.Raw["message"] = msg.Message
+	}
+	d.runEventHooks(adapterID, evt)
+}
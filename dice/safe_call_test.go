@@ -0,0 +1,152 @@
+package dice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sealdice/smallseal/dice/exts"
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+func newGuardTestDice(t *testing.T) (*testDice, *[]*types.AdapterEvent, <-chan error) {
+	d := newTestDice(t, nil)
+
+	events := &[]*types.AdapterEvent{}
+	_, err := d.RegisterEventHook("collect", types.HookPriorityNormal, func(_ types.DiceLike, _ string, evt *types.AdapterEvent) types.HookResult {
+		*events = append(*events, evt)
+		return types.HookResultContinue
+	})
+	assert.NoError(t, err)
+
+	slowDone := make(chan error, 1)
+	d.RegisterExtension(&types.ExtInfo{
+		Name:       "guard",
+		AutoActive: true,
+		CmdMap: types.CmdMapCls{
+			"boom": &types.CmdItemInfo{
+				Name: "boom",
+				Solve: func(*types.MsgContext, *types.Message, *types.CmdArgs) types.CmdExecuteResult {
+					panic("boom")
+				},
+			},
+			"slow": &types.CmdItemInfo{
+				Name: "slow",
+				Solve: func(ctx *types.MsgContext, msg *types.Message, _ *types.CmdArgs) types.CmdExecuteResult {
+					time.Sleep(200 * time.Millisecond)
+					exts.ReplyToSender(ctx, msg, "slow")
+					slowDone <- ctx.Context().Err()
+					return types.CmdExecuteResult{Matched: true, Solved: true}
+				},
+			},
+		},
+	})
+	return d, events, slowDone
+}
+
+func sendGuardTestMessage(d *testDice, content string) *ExecuteResult {
+	return d.exec(groupMessage("QQ:1", "tester", "", content))
+}
+
+func TestSolvePanicBecomesErrorReply(t *testing.T) {
+	as := assert.New(t)
+	d, events, _ := newGuardTestDice(t)
+
+	as.NotPanics(func() {
+		result := sendGuardTestMessage(d, ".boom")
+		as.True(result.Solved)
+		as.Error(result.Err)
+	})
+	as.Equal([]string{"指令执行异常，请联系开发者，群号524364253，非常感谢。"}, d.texts())
+	if as.Len(*events, 1) {
+		evt := (*events)[0]
+		as.Equal(EventPostTypeInternal, evt.PostType)
+		as.Equal(EventTypePanic, evt.Type)
+		as.Equal("solve:boom", evt.SubType)
+		as.Equal("QQ:1", evt.UserID)
+	}
+
+	// 之后的指令不受影响
+	sendGuardTestMessage(d, ".r d20")
+	as.Len(d.texts(), 1)
+}
+
+func TestSolveTimeout(t *testing.T) {
+	as := assert.New(t)
+	d, events, slowDone := newGuardTestDice(t)
+	as.Zero(d.Config.ExecuteTimeout, "timeout is opt-in")
+	d.Config.ExecuteTimeout = 20 * time.Millisecond
+
+	sendGuardTestMessage(d, ".slow")
+	as.Equal([]string{"指令执行超时，已放弃等待结果。"}, d.texts())
+	if as.Len(*events, 1) {
+		as.Equal(EventTypeTimeout, (*events)[0].Type)
+	}
+
+	// 超时的指令返回后才继续处理，它看到 context 已取消，其回复被丢弃
+	as.Len(slowDone, 1, "execute waits for the timed out command")
+	as.ErrorIs(<-slowDone, context.Canceled)
+	as.Equal([]string{"指令执行超时，已放弃等待结果。"}, d.texts())
+
+	// 未超时的指令正常回复
+	d.Config.ExecuteTimeout = time.Second
+	sendGuardTestMessage(d, ".slow")
+	as.NoError(<-slowDone)
+	as.Equal([]string{"slow"}, d.texts())
+}
+
+func TestHookPanicIsIsolated(t *testing.T) {
+	as := assert.New(t)
+	d, events, _ := newGuardTestDice(t)
+
+	_, err := d.RegisterMessageInHook("bad-in", types.HookPriorityHigh, func(types.DiceLike, string, *types.Message, *types.MsgContext) types.HookResult {
+		panic("in")
+	})
+	as.NoError(err)
+	_, err = d.RegisterMessageOutHook("bad-out", types.HookPriorityHigh, func(types.DiceLike, string, *types.MsgToReply) types.HookResult {
+		panic("out")
+	})
+	as.NoError(err)
+	_, err = d.RegisterEventHook("bad-event", types.HookPriorityHigh, func(types.DiceLike, string, *types.AdapterEvent) types.HookResult {
+		panic("event")
+	})
+	as.NoError(err)
+
+	as.NotPanics(func() {
+		sendGuardTestMessage(d, ".r d20")
+	})
+	as.Len(d.texts(), 1, "reply should still be delivered")
+
+	subTypes := []string{}
+	for _, evt := range *events {
+		subTypes = append(subTypes, evt.SubType)
+	}
+	as.Equal([]string{"hook:in:bad-in", "hook:out:bad-out"}, subTypes)
+}
+
+func TestHookTimeout(t *testing.T) {
+	as := assert.New(t)
+	d, events, _ := newGuardTestDice(t)
+	d.Config.ExecuteTimeout = 20 * time.Millisecond
+
+	hookDone := make(chan error, 1)
+	_, err := d.RegisterMessageInHook("slow-in", types.HookPriorityHigh, func(_ types.DiceLike, _ string, msg *types.Message, ctx *types.MsgContext) types.HookResult {
+		time.Sleep(100 * time.Millisecond)
+		exts.ReplyToSender(ctx, msg, "hook")
+		hookDone <- ctx.Context().Err()
+		return types.HookResultAbort
+	})
+	as.NoError(err)
+
+	result := sendGuardTestMessage(d, "hello")
+	as.Len(hookDone, 1, "execute waits for the timed out hook")
+	as.ErrorIs(<-hookDone, context.Canceled)
+	as.False(result.HookAborted, "result of a timed out hook is ignored")
+	as.Empty(d.texts(), "reply of a timed out hook is dropped")
+	if as.Len(*events, 1) {
+		as.Equal(EventTypeTimeout, (*events)[0].Type)
+		as.Equal("hook:in:slow-in", (*events)[0].SubType)
+	}
+}
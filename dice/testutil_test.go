@@ -0,0 +1,83 @@
+package dice
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+// testDice 测试用的骰子，通过出站钩子收集经由任意适配器发出的回复
+// 收集可在管线的 worker 中并发进行
+type testDice struct {
+	*Dice
+	adapterID string // 消息的来源适配器，默认为 test
+
+	mu      sync.Mutex
+	replies []*types.MsgToReply
+}
+
+// newTestDice 创建骰子并注册名为 test 的适配器，cfg 不为 nil 时先应用该配置
+func newTestDice(t *testing.T, cfg *Config) *testDice {
+	t.Helper()
+	td := &testDice{Dice: NewDice(), adapterID: "test"}
+	if cfg != nil {
+		require.NoError(t, td.ApplyConfig(*cfg))
+	}
+	require.NoError(t, td.RegisterAdapterSender("test", func(*types.MsgToReply) {}))
+	_, err := td.RegisterMessageOutHook("test:collect", types.HookPriorityLow, func(_ types.DiceLike, _ string, msg *types.MsgToReply) types.HookResult {
+		td.mu.Lock()
+		td.replies = append(td.replies, msg)
+		td.mu.Unlock()
+		return types.HookResultContinue
+	})
+	require.NoError(t, err)
+	return td
+}
+
+// exec 清空已收集的回复后处理消息
+func (td *testDice) exec(msg *types.Message) *ExecuteResult {
+	td.mu.Lock()
+	td.replies = nil
+	td.mu.Unlock()
+	return td.Execute(td.adapterID, msg)
+}
+
+// send 处理消息，返回第一条回复的文本
+func (td *testDice) send(msg *types.Message) string {
+	td.exec(msg)
+	return td.reply()
+}
+
+// reply 第一条回复的文本，没有回复时为空
+func (td *testDice) reply() string {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+	if len(td.replies) == 0 {
+		return ""
+	}
+	return td.replies[0].Segments.ToText()
+}
+
+// texts 全部回复的文本
+func (td *testDice) texts() []string {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+	var texts []string
+	for _, msg := range td.replies {
+		texts = append(texts, msg.Segments.ToText())
+	}
+	return texts
+}
+
+// groupMessage 构造 QQ-Group:1 中的文本消息
+func groupMessage(userID string, nickname string, role string, content string) *types.Message {
+	return &types.Message{
+		MessageType: "group",
+		GroupID:     "QQ-Group:1",
+		Sender:      types.SenderBase{UserID: userID, Nickname: nickname, GroupRole: role},
+		Segments:    types.MessageSegments{&types.TextElement{Content: content}},
+	}
+}
@@ -364,6 +364,9 @@ var DefaultTextMap = types.TextTemplateWithWeightDict{
 		"骰子执行异常": {
 			{"指令执行异常，请联系开发者，群号524364253，非常感谢。", 1},
 		},
+		"骰子执行超时": {
+			{"指令执行超时，已放弃等待结果。", 1},
+		},
 		"骰子开启": {
 			{"{常量:APPNAME} 已启用 {常量:VERSION}", 1},
 		},
@@ -379,6 +382,9 @@ var DefaultTextMap = types.TextTemplateWithWeightDict{
 		"骰子退群预告": {
 			{"收到指令，5s后将退出当前群组", 1},
 		},
+		"骰子自动退群预告": {
+			{"本群已{$t未使用天数}天没有使用过指令，{核心:骰子名字}将在{$t退群倒计时}后退出本群。如需继续使用，请在此之前发送任意指令。", 1},
+		},
 		"骰子自动退群告别语": {
 			{"由于长时间不使用，{核心:骰子名字}将退出本群，感谢您的使用。", 1},
 		},
@@ -494,6 +500,9 @@ var DefaultTextMap = types.TextTemplateWithWeightDict{
 		"提示_无权限_非master/管理": {
 			{"你不是管理员或master", 1},
 		},
+		"入群欢迎语_默认": {
+			{"欢迎{$t新成员}加入本群，祝你玩得愉快", 1},
+		},
 		"提示_手动退群前缀": {
 			{"因长期不使用等原因，骰主后台操作退群", 1},
 		},
@@ -3,6 +3,7 @@ package types
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"unicode"
 )
@@ -44,6 +45,7 @@ type CmdArgs struct {
 	prefixStr                  string    // 命令前导符号，这几个用于基于当前cmdArgs信息重走解析流程，暂不对js开放
 	platformPrefix             string    // 平台前缀
 	uidForAtInfo               string    // 用于处理@的uid
+	executeTimesParsed         bool      // 是否从文本中取出过 N#
 
 	MentionedOtherDice bool   // 似乎没有在用
 	CleanArgsChopRest  string // 未来可能移除
@@ -139,24 +141,45 @@ func (cmdArgs *CmdArgs) GetRestArgsFrom(index int) string {
 }
 
 // RevokeExecuteTimesParse 因为次数解析进行的太早了，影响太大无法还原，这里干脆重新解析一遍
-// func (cmdArgs *CmdArgs) RevokeExecuteTimesParse(ctx *MsgContext, msg *Message) {
-// 	// 对于使用消息段的信息，使用新的解析方式
-// 	if len(msg.Segment) > 0 {
-// 		cmdArgs.commandParseNew(ctx, msg, true)
-// 	} else {
-// 		cmdArgs.commandParse(cmdArgs.RawText, []string{cmdArgs.Command}, []string{cmdArgs.prefixStr}, cmdArgs.platformPrefix, true)
-// 		cmdArgs.SetupAtInfo(cmdArgs.uidForAtInfo)
-// 	}
-// }
+// 用于指令未开启 EnableExecuteTimesParse 的情况，@信息保持不变
+func (cmdArgs *CmdArgs) RevokeExecuteTimesParse() {
+	if !cmdArgs.executeTimesParsed {
+		return
+	}
+	at := cmdArgs.At
+	reparsed := new(CmdArgs).commandParse(cmdArgs.RawText, []string{cmdArgs.Command}, []string{cmdArgs.prefixStr}, cmdArgs.platformPrefix, false)
+	if reparsed == nil {
+		return
+	}
+	uid := cmdArgs.uidForAtInfo
+	*cmdArgs = *reparsed
+	cmdArgs.At = at
+	cmdArgs.SetupAtInfo(uid)
+}
+
+var reExecuteTimes = regexp.MustCompile(`^(\d+)#`)
+
+// SpecialExecuteTimesParse 取出文本开头的 N# 执行次数，如 3#ra 侦查 中的 3#
+// 指令解析时只对先导符号之后与参数开头调用，如 .3#ra 侦查、.r 5#d20，其余位置的 N# 作为普通文本保留
+func SpecialExecuteTimesParse(text string) (string, int) {
+	m := reExecuteTimes.FindStringSubmatch(text)
+	if m == nil {
+		return text, 0
+	}
+	times, _ := strconv.Atoi(m[1])
+	return text[len(m[0]):], times
+}
 
+// SetupAtInfo 根据骰子自身的ID设置被@状态，未带平台前缀的ID按解析时的平台前缀补全
 func (cmdArgs *CmdArgs) SetupAtInfo(uid string) {
+	uid = EnsureDiceID(cmdArgs.platformPrefix, uid)
 	// 设置AmIBeMentioned
 	cmdArgs.AmIBeMentioned = false
 	cmdArgs.AmIBeMentionedFirst = false
 	cmdArgs.uidForAtInfo = uid
 
 	for _, i := range cmdArgs.At {
-		if i.UserID == uid {
+		if uid != "" && i.UserID == uid {
 			cmdArgs.AmIBeMentioned = true
 			break
 		}
@@ -174,6 +197,25 @@ func (cmdArgs *CmdArgs) SetupAtInfo(uid string) {
 	cmdArgs.SomeoneBeMentionedButNotMe = len(cmdArgs.At) > 0 && (!cmdArgs.AmIBeMentioned)
 }
 
+// AtParse 取出消息中的@，返回去除@后的文本，其余非文本元素以 $N 占位，与 MessageSegments.ToText 一致
+// 被@的用户ID按平台前缀补全，如 QQ:123、TG:456
+func AtParse(segments MessageSegments, platformPrefix string) (string, []*AtInfo) {
+	var atInfo []*AtInfo
+	var builder strings.Builder
+	for idx, elem := range segments {
+		switch e := elem.(type) {
+		case *TextElement:
+			builder.WriteString(e.Content)
+		case *AtElement:
+			atInfo = append(atInfo, &AtInfo{UserID: EnsureDiceID(platformPrefix, e.Target)})
+		default:
+			builder.WriteByte('$')
+			builder.WriteString(strconv.Itoa(idx + 1))
+		}
+	}
+	return strings.TrimSpace(builder.String()), atInfo
+}
+
 var reSpace = regexp.MustCompile(`\s+`)
 var reKeywordParam = regexp.MustCompile(`^--([^\s=]+)(?:=(\S+))?$`)
 
@@ -211,11 +253,8 @@ func (cmdArgs *CmdArgs) commandParse(rawCmd string, currentCmdLst []string, pref
 	rawCmd = strings.ReplaceAll(rawCmd, "\r\n", "\n") // 替换\r\n为\n
 
 	restText := rawCmd
-	// restText, atInfo := AtParse(rawCmd, platformPrefix)
-	// restText = strings.TrimSpace(restText)
-	// if isParseExecuteTimes {
-	// 	restText, specialExecuteTimes = SpecialExecuteTimesParse(restText)
-	// }
+	// @ 由 AtParse 在解析前取出
+	executeTimesParsed := false
 
 	// 先导符号检测
 	var prefixStr string
@@ -232,6 +271,13 @@ func (cmdArgs *CmdArgs) commandParse(rawCmd string, currentCmdLst []string, pref
 	restText = strings.TrimSpace(restText) // 清除剩余文本的空格，以兼容. rd20 形式
 	isSpaceBeforeArgs := false
 
+	// 先导符号之后的 N#，如 .3#ra 侦查
+	if isParseExecuteTimes {
+		before := restText
+		restText, specialExecuteTimes = SpecialExecuteTimesParse(restText)
+		executeTimesParsed = restText != before
+	}
+
 	// 兼容模式，进行格式化
 	// 之前的 commandCompatibleMode 现在不再有兼容模式的区分
 	if strings.HasPrefix(restText, "bot list") {
@@ -264,6 +310,12 @@ func (cmdArgs *CmdArgs) commandParse(rawCmd string, currentCmdLst []string, pref
 	m := re.FindStringSubmatch(restText)
 
 	if len(m) == 3 {
+		// 参数开头的 N#，如 .r 5#d20
+		if isParseExecuteTimes && !executeTimesParsed {
+			before := m[2]
+			m[2], specialExecuteTimes = SpecialExecuteTimesParse(m[2])
+			executeTimesParsed = m[2] != before
+		}
 		cmdArgs.Command = m[1]
 		cmdArgs.RawArgs = m[2]
 		// cmdArgs.At = atInfo
@@ -286,6 +338,7 @@ func (cmdArgs *CmdArgs) commandParse(rawCmd string, currentCmdLst []string, pref
 		cmdArgs.RawText = rawCmd
 		cmdArgs.prefixStr = prefixStr
 		cmdArgs.platformPrefix = platformPrefix
+		cmdArgs.executeTimesParsed = executeTimesParsed
 
 		return cmdArgs
 	}
@@ -0,0 +1,44 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpecialExecuteTimesParse(t *testing.T) {
+	as := assert.New(t)
+
+	text, times := SpecialExecuteTimesParse("3#ra 侦查 原因是第2#个")
+	as.Equal(3, times)
+	as.Equal("ra 侦查 原因是第2#个", text)
+
+	text, times = SpecialExecuteTimesParse("ra 侦查 原因是第2#关")
+	as.Equal(0, times)
+	as.Equal("ra 侦查 原因是第2#关", text)
+
+	parse := func(text string) *CmdArgs {
+		return CommandParse(text, []string{"ra", "r"}, []string{"."}, "QQ", true)
+	}
+
+	cmdArgs := parse(".3#ra 侦查 原因是第2#个")
+	as.Equal(3, cmdArgs.SpecialExecuteTimes)
+	as.Equal("ra", cmdArgs.Command)
+	as.Equal("侦查 原因是第2#个", cmdArgs.CleanArgs)
+
+	cmdArgs = parse(".r 5#d20")
+	as.Equal(5, cmdArgs.SpecialExecuteTimes)
+	as.Equal("d20", cmdArgs.CleanArgs)
+
+	// 只认先导符号之后与参数开头的 N#
+	cmdArgs = parse(".ra 侦查 原因是第2#关")
+	as.Equal(0, cmdArgs.SpecialExecuteTimes)
+	as.Equal("侦查 原因是第2#关", cmdArgs.CleanArgs)
+	cmdArgs.RevokeExecuteTimesParse()
+	as.Equal("侦查 原因是第2#关", cmdArgs.CleanArgs)
+
+	cmdArgs = parse(".r 5#d20")
+	cmdArgs.RevokeExecuteTimesParse()
+	as.Equal(0, cmdArgs.SpecialExecuteTimes)
+	as.Equal("5#d20", cmdArgs.CleanArgs)
+}
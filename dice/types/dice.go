@@ -1,6 +1,10 @@
 package types
 
-import "github.com/sealdice/smallseal/utils"
+import (
+	"time"
+
+	"github.com/sealdice/smallseal/utils"
+)
 
 type DiceLike interface {
 	RegisterExtension(extInfo *ExtInfo)
@@ -15,7 +19,10 @@ type DiceLike interface {
 	IsMaster(uid string) bool
 
 	PersistGroupInfo(groupID string, info *GroupInfo)
-	SendReply(msg *MsgToReply)
+	QuitGroup(adapterID string, groupID string) error
+	ScheduleQuitGroup(adapterID string, groupID string, delay time.Duration)
+	SendReply(msg *MsgToReply) error
+	SendFile(adapterID string, messageType string, targetID string, path string) error
 
 	RegisterMessageInHook(name string, priority HookPriority, hook MessageInHook) (HookHandle, error)
 	UnregisterMessageInHook(handle HookHandle) bool
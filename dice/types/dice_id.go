@@ -0,0 +1,26 @@
+package types
+
+import "strings"
+
+// FormatDiceID 拼接带平台前缀的用户ID，如 QQ:123、TG:456
+func FormatDiceID(platform, userID string) string {
+	return platform + ":" + userID
+}
+
+// FormatDiceIDGroup 拼接带平台前缀的群组ID，如 QQ-Group:123
+func FormatDiceIDGroup(platform, groupID string) string {
+	return platform + "-Group:" + groupID
+}
+
+// FormatDiceIDCh 拼接带平台前缀的频道用户ID，如 QQ-CH:123
+func FormatDiceIDCh(platform, userID string) string {
+	return platform + "-CH:" + userID
+}
+
+// EnsureDiceID 为未带平台前缀的用户ID补全前缀，已带前缀的ID原样返回
+func EnsureDiceID(platform, userID string) string {
+	if userID == "" || platform == "" || strings.Contains(userID, ":") {
+		return userID
+	}
+	return FormatDiceID(platform, userID)
+}
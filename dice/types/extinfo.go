@@ -18,6 +18,7 @@ type CmdItemInfo struct {
 	AllowDelegate           bool                      `jsbind:"allowDelegate"`           // 允许代骰
 	DisabledInPrivate       bool                      `jsbind:"disabledInPrivate"`       // 私聊不可用
 	EnableExecuteTimesParse bool                      `jsbind:"enableExecuteTimesParse"` // 启用执行次数解析，也就是解析3#这样的文本
+	RequiredPrivilege       int                       `jsbind:"requiredPrivilege"`       // 所需权限等级，不足时不进入solve
 
 	IsJsSolveFunc bool
 	JSLoopVersion int64                                                                  // Loop版本号
@@ -62,7 +63,7 @@ type ExtInfo struct {
 
 	// TODO: 下面这些有没有还要再看，可能会极大省略
 	OnNotCommandReceived func(ctx *MsgContext, msg *Message)                        `jsbind:"onNotCommandReceived" json:"-" yaml:"-"` // 指令过滤后剩下的
-	OnCommandOverride    func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) bool `json:"-"                      yaml:"-"`          // 覆盖指令行为
+	OnCommandOverride    func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) bool `json:"-"                      yaml:"-"`          // 覆盖指令行为，在正常分发前按扩展优先级调用，可拦截其他扩展的指令，返回 true 表示已处理
 
 	OnCommandReceived   func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) `jsbind:"onCommandReceived"   json:"-" yaml:"-"`
 	OnMessageReceived   func(ctx *MsgContext, msg *Message)                   `jsbind:"onMessageReceived"   json:"-" yaml:"-"`
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"sort"
 	"strings"
 
 	"github.com/sealdice/dicescript"
@@ -46,7 +47,7 @@ type GroupPlayerInfo struct {
 type GroupInfo struct {
 	Active           bool                                     `jsbind:"active"         json:"active"                yaml:"active"` // 是否在群内开启 - 过渡为象征意义
 	ActivatedExtList []*ExtInfo                               `json:"activatedExtList" yaml:"activatedExtList,flow"`               // 当前群开启的扩展列表
-	ExtListSnapshot  []string                                 `json:"-"                yaml:"-"`                                   // 存放当前激活的扩展表，无论其是否存在，用于处理插件重载后优先级混乱的问题
+	ExtListSnapshot  []string                                 `json:"extListSnapshot"  yaml:"extListSnapshot,flow"`                // 群内扩展优先级，按扩展名记录且不要求扩展存在，用于处理插件重载后优先级混乱的问题
 	ExtActiveStates  *utils.SyncMap[string, bool]             `json:"extActiveStates"  yaml:"extActiveStates,flow"`                // 扩展激活状态映射表，key为扩展名，value为是否激活
 	Players          *utils.SyncMap[string, *GroupPlayerInfo] `json:"-"                yaml:"-"`                                   // 群员角色数据
 
@@ -55,11 +56,12 @@ type GroupInfo struct {
 	ChannelID string `jsbind:"channelId"     json:"channelId"    yaml:"channelId"`
 	GroupName string `jsbind:"groupName"     json:"groupName"    yaml:"groupName"`
 
-	DiceIDActiveMap *utils.SyncMap[string, bool] `json:"diceIdActiveMap" yaml:"diceIds,flow"` // 对应的骰子ID(格式 平台:ID)，对应单骰多号情况，例如骰A B都加了群Z，A退群不会影响B在群内服务
-	DiceIDExistsMap *utils.SyncMap[string, bool] `json:"diceIdExistsMap" yaml:"-"`            // 对应的骰子ID(格式 平台:ID)是否存在于群内
-	BotList         *utils.SyncMap[string, bool] `json:"botList"         yaml:"botList,flow"` // 其他骰子列表
-	DiceSideExpr    string                       `json:"diceSideExpr"    yaml:"diceSideExpr"` //
-	System          string                       `json:"system"          yaml:"system"`       // 规则系统，概念同bcdice的gamesystem，例如dnd5e coc7
+	DiceIDActiveMap     *utils.SyncMap[string, bool] `json:"diceIdActiveMap" yaml:"diceIds,flow"`            // 各骰子账号(见 DiceAccountID)的开关状态，对应单骰多号情况，例如骰A B都加了群Z，A退群不会影响B在群内服务
+	DiceIDExistsMap     *utils.SyncMap[string, bool] `json:"diceIdExistsMap" yaml:"-"`                       // 各骰子账号(见 DiceAccountID)是否存在于群内
+	DiceIDActiveDefault bool                         `json:"diceIdActiveDefault" yaml:"diceIdActiveDefault"` // DiceIDActiveMap 中没有记录的账号的开关，取首次单独设置前的 Active
+	BotList             *utils.SyncMap[string, bool] `json:"botList"         yaml:"botList,flow"`            // 其他骰子列表
+	DiceSideExpr        string                       `json:"diceSideExpr"    yaml:"diceSideExpr"`            //
+	System              string                       `json:"system"          yaml:"system"`                  // 规则系统，概念同bcdice的gamesystem，例如dnd5e coc7
 	// DiceSideNum     int64                        `json:"diceSideNum"     yaml:"diceSideNum"`  // 以后可能会支持 1d4 这种默认面数，暂不开放给js
 
 	HelpPackages []string `json:"helpPackages"   yaml:"-"`
@@ -73,13 +75,14 @@ type GroupInfo struct {
 	ShowGroupWelcome    bool   `jsbind:"showGroupWelcome"    json:"showGroupWelcome"    yaml:"showGroupWelcome"` // 是否迎新
 	GroupWelcomeMessage string `jsbind:"groupWelcomeMessage" json:"groupWelcomeMessage" yaml:"groupWelcomeMessage"`
 	// FirstSpeechMade     bool   `yaml:"firstSpeechMade"` // 是否做过进群发言
-	LastCustomReplyTime float64 `json:"-" yaml:"-"` // 上次自定义回复时间
+	LastCustomReplyTime *utils.SyncMap[string, float64] `json:"-" yaml:"-"` // 自定义回复各规则的上次触发时间(秒)，key为规则名
 
 	RateLimiter     *rate.Limiter `json:"-" yaml:"-"`
 	RateLimitWarned bool          `json:"-" yaml:"-"`
 
 	EnteredTime  int64  `jsbind:"enteredTime"  json:"enteredTime"  yaml:"enteredTime"`  // 入群时间
 	InviteUserID string `jsbind:"inviteUserId" json:"inviteUserId" yaml:"inviteUserId"` // 邀请人
+	AdapterId    string `jsbind:"adapterId"    json:"adapterId"    yaml:"adapterId"`    // 最近收到本群消息的适配器
 	// 仅用于http接口
 	TmpPlayerNum int64    `json:"tmpPlayerNum" yaml:"-"`
 	TmpExtList   []string `json:"tmpExtList"   yaml:"-"`
@@ -91,6 +94,69 @@ type GroupInfo struct {
 	PlayerGroups *utils.SyncMap[string, []string] `json:"playerGroups" yaml:"playerGroups"` // 供team指令使用并由其管理，与Players不同步
 }
 
+// DiceAccountID 骰子账号标识，由适配器ID与账号ID组成，适配器未提供账号ID时仅为适配器ID
+func DiceAccountID(adapterId string, selfId string) string {
+	if selfId == "" {
+		return adapterId
+	}
+	return adapterId + ":" + selfId
+}
+
+// IsDiceActive 指定骰子账号在群内是否开启
+// 群内没有账号单独设置过时沿用 Active，否则未设置过的账号使用 DiceIDActiveDefault
+func (g *GroupInfo) IsDiceActive(diceID string) bool {
+	if g.DiceIDActiveMap == nil || g.DiceIDActiveMap.Len() == 0 || diceID == "" {
+		return g.Active
+	}
+	if active, ok := g.DiceIDActiveMap.Load(diceID); ok {
+		return active
+	}
+	return g.DiceIDActiveDefault
+}
+
+// SetDiceActive 设置指定骰子账号在群内的开关，Active 随之更新为是否有账号开启
+// 首次单独设置时，群内已知的其他账号按原先的 Active 记录，不受这次设置影响
+func (g *GroupInfo) SetDiceActive(diceID string, active bool) {
+	if diceID == "" {
+		g.Active = active
+		return
+	}
+	if g.DiceIDActiveMap == nil {
+		g.DiceIDActiveMap = &utils.SyncMap[string, bool]{}
+	}
+	if g.DiceIDActiveMap.Len() == 0 {
+		g.DiceIDActiveDefault = g.Active
+		if g.DiceIDExistsMap != nil {
+			g.DiceIDExistsMap.Range(func(id string, _ bool) bool {
+				g.DiceIDActiveMap.Store(id, g.Active)
+				return true
+			})
+		}
+	}
+	g.DiceIDActiveMap.Store(diceID, active)
+	g.refreshActive()
+}
+
+// RemoveDice 移除退群的骰子账号，不影响群内的其他账号
+func (g *GroupInfo) RemoveDice(diceID string) {
+	if g.DiceIDExistsMap != nil {
+		g.DiceIDExistsMap.Delete(diceID)
+	}
+	if g.DiceIDActiveMap != nil {
+		g.DiceIDActiveMap.Delete(diceID)
+		g.refreshActive()
+	}
+}
+
+func (g *GroupInfo) refreshActive() {
+	active := false
+	g.DiceIDActiveMap.Range(func(_ string, value bool) bool {
+		active = value
+		return !value
+	})
+	g.Active = active
+}
+
 // IsExtensionActive 检查指定扩展是否在当前群组中激活
 func (g *GroupInfo) IsExtensionActive(extName string) bool {
 	if g.ExtActiveStates == nil {
@@ -109,11 +175,23 @@ func (g *GroupInfo) SetExtensionActive(extName string, active bool) {
 }
 
 // GetActiveExtensions 获取当前群组中所有激活的扩展列表
+//
+// 返回顺序即同名指令的归属顺序，排在前面的扩展优先处理指令：
+// core 最先，其次按 ExtListSnapshot 中设置的优先级，再次是与当前规则(System)同名的扩展，其余按注册顺序。
+// 未记录状态的自动开启扩展若与已开启的扩展互斥，则记为关闭。
 func (g *GroupInfo) GetActiveExtensions(allExtensions []*ExtInfo) []*ExtInfo {
 	var coreExts []*ExtInfo
+	var priorityExts []*ExtInfo
 	var systemExts []*ExtInfo
 	var otherExts []*ExtInfo
 	systemName := strings.ToLower(g.System)
+	priority := map[string]int{}
+	for idx, name := range g.ExtListSnapshot {
+		name = strings.ToLower(name)
+		if _, exists := priority[name]; !exists {
+			priority[name] = idx
+		}
+	}
 
 	isActive := func(ext *ExtInfo) bool {
 		if g.ExtActiveStates != nil {
@@ -122,8 +200,9 @@ func (g *GroupInfo) GetActiveExtensions(allExtensions []*ExtInfo) []*ExtInfo {
 			}
 		}
 		if ext.AutoActive {
-			g.SetExtensionActive(ext.Name, true)
-			return true
+			active := len(g.ExtConflicts(ext, allExtensions)) == 0
+			g.SetExtensionActive(ext.Name, active)
+			return active
 		}
 		return false
 	}
@@ -132,9 +211,12 @@ func (g *GroupInfo) GetActiveExtensions(allExtensions []*ExtInfo) []*ExtInfo {
 		if ext == nil || !isActive(ext) {
 			continue
 		}
+		_, hasPriority := priority[strings.ToLower(ext.Name)]
 		switch {
 		case strings.EqualFold(ext.Name, "core"):
 			coreExts = append(coreExts, ext)
+		case hasPriority:
+			priorityExts = append(priorityExts, ext)
 		case systemName != "" && strings.EqualFold(ext.Name, systemName):
 			systemExts = append(systemExts, ext)
 		default:
@@ -142,7 +224,13 @@ func (g *GroupInfo) GetActiveExtensions(allExtensions []*ExtInfo) []*ExtInfo {
 		}
 	}
 
-	return append(append(coreExts, systemExts...), otherExts...)
+	sort.SliceStable(priorityExts, func(i, j int) bool {
+		return priority[strings.ToLower(priorityExts[i].Name)] < priority[strings.ToLower(priorityExts[j].Name)]
+	})
+
+	result := append(coreExts, priorityExts...)
+	result = append(result, systemExts...)
+	return append(result, otherExts...)
 }
 
 func (g *GroupInfo) ensureExtStates() {
@@ -174,6 +262,48 @@ func (g *GroupInfo) ExtActive(ext *ExtInfo) {
 	}
 }
 
+// ExtIsConflict 判断两个扩展是否互斥，任意一方在 ConflictWith 中声明了对方即视为互斥
+func ExtIsConflict(a, b *ExtInfo) bool {
+	if a == nil || b == nil || strings.EqualFold(a.Name, b.Name) {
+		return false
+	}
+	for _, name := range a.ConflictWith {
+		if strings.EqualFold(name, b.Name) {
+			return true
+		}
+	}
+	for _, name := range b.ConflictWith {
+		if strings.EqualFold(name, a.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtConflicts 返回当前群组中已开启、且与 ext 互斥的扩展
+func (g *GroupInfo) ExtConflicts(ext *ExtInfo, allExtensions []*ExtInfo) []*ExtInfo {
+	var conflicts []*ExtInfo
+	for _, other := range allExtensions {
+		if other != nil && g.IsExtensionActive(other.Name) && ExtIsConflict(ext, other) {
+			conflicts = append(conflicts, other)
+		}
+	}
+	return conflicts
+}
+
+// ExtActiveExclusive 开启扩展并关闭与其互斥的扩展，返回被关闭的扩展
+func (g *GroupInfo) ExtActiveExclusive(ext *ExtInfo, allExtensions []*ExtInfo) []*ExtInfo {
+	if ext == nil {
+		return nil
+	}
+	conflicts := g.ExtConflicts(ext, allExtensions)
+	for _, other := range conflicts {
+		g.ExtInactiveByName(other.Name)
+	}
+	g.ExtActive(ext)
+	return conflicts
+}
+
 // ExtInactiveByName 关闭指定扩展，并返回被关闭的扩展信息
 func (g *GroupInfo) ExtInactiveByName(name string) *ExtInfo {
 	if name == "" {
@@ -32,6 +32,22 @@ type AdapterEvent struct {
 	Raw        map[string]any // 原始事件数据，便于扩展
 }
 
+// 会分发到扩展回调的事件类型，适配器应将平台事件转换为以下取值
+const (
+	AdapterEventGroupIncrease = "group_increase" // 群成员增加，UserID 为骰子自身时视为骰子入群
+	AdapterEventFriendAdd     = "friend_add"     // 新增好友
+	AdapterEventGroupRecall   = "group_recall"   // 群消息撤回
+	AdapterEventFriendRecall  = "friend_recall"  // 私聊消息撤回
+	AdapterEventMessageEdit   = "message_edit"   // 消息编辑
+	AdapterEventGuildJoined   = "guild_joined"   // 骰子加入频道服务器
+)
+
+// 申请事件类型，OneBot 的 friend 与 group(sub_type=invite) 同样会被识别
+const (
+	AdapterEventFriendRequest = "friend_request"   // 好友申请
+	AdapterEventGroupInvite   = "group_invitation" // 入群邀请
+)
+
 type MessageInHook func(d DiceLike, adapterID string, msg *Message, ctx *MsgContext) HookResult
 
 type MessageOutHook func(d DiceLike, adapterID string, reply *MsgToReply) HookResult
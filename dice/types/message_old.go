@@ -23,6 +23,7 @@ type Message struct {
 	Message     string     `jsbind:"message"     json:"message"`  // 消息内容
 	RawID       any        `jsbind:"rawId"       json:"rawId"`    // 原始信息ID，用于处理撤回等
 	Platform    string     `jsbind:"platform"    json:"platform"` // 当前平台
+	SelfID      string     `jsbind:"selfId"      json:"selfId"`   // 收到消息的骰子账号，如 QQ:123，适配器未提供时为空
 	GroupName   string     `json:"groupName"`
 	// Note(Szzrain): 这里是消息段，为了支持多种消息类型，目前只有 Milky 支持，其他平台也应该尽快迁移支持，并使用 Session.ExecuteNew 方法
 	Segments MessageSegments `jsbind:"segment" json:"segments" yaml:"-"`
@@ -0,0 +1,49 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 聊天场景，对应 Message.MessageType 与 MsgContext.MessageScene
+const (
+	MessageSceneGroup   = "group"   // 群聊
+	MessageScenePrivate = "private" // 私聊
+	MessageSceneGuild   = "guild"   // 频道服务器中的子频道，如QQ频道
+	MessageSceneChannel = "channel" // 独立频道，如 discord、kook 的文字频道
+)
+
+const channelGroupIDMark = "-CH-Group:"
+
+// IsGroupScene 是否为多人场景，群聊和频道消息都有各自的群组信息
+func IsGroupScene(messageType string) bool {
+	switch messageType {
+	case MessageSceneGroup, MessageSceneGuild, MessageSceneChannel:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsChannelScene 是否为频道消息，这类消息的群组由服务器与子频道共同确定
+func IsChannelScene(messageType string) bool {
+	return messageType == MessageSceneGuild || messageType == MessageSceneChannel
+}
+
+// ChannelGroupID 子频道对应的群组ID，每个子频道各自一份群组信息，形如 QQ-CH-Group:服务器-子频道
+func ChannelGroupID(platform, guildID, channelID string) string {
+	return fmt.Sprintf("%s%s%s-%s", platform, channelGroupIDMark, guildID, channelID)
+}
+
+// ParseChannelGroupID 从 ChannelGroupID 生成的群组ID中取出服务器与子频道ID
+func ParseChannelGroupID(groupID string) (guildID string, channelID string, ok bool) {
+	idx := strings.Index(groupID, channelGroupIDMark)
+	if idx < 0 {
+		return "", "", false
+	}
+	guildID, channelID, ok = strings.Cut(groupID[idx+len(channelGroupIDMark):], "-")
+	if !ok || guildID == "" || channelID == "" {
+		return "", "", false
+	}
+	return guildID, channelID, true
+}
@@ -1,7 +1,11 @@
 package types
 
 import (
+	"context"
+
 	"github.com/sealdice/smallseal/dice/attrs"
+	"github.com/sealdice/smallseal/dice/ban"
+	"github.com/sealdice/smallseal/dice/helpdoc"
 	"github.com/sealdice/smallseal/utils"
 
 	"github.com/samber/lo"
@@ -22,9 +26,17 @@ type CommandFormatInfo struct {
 type MsgContext struct {
 	CommandId int64
 	AdapterId string
+	Platform  string // 消息来源适配器的平台，用于补全 QQ:123 形式的ID
+	DiceID    string // 收到消息的骰子账号，见 DiceAccountID
 
 	IsCurGroupBotOn bool
 	IsPrivate       bool
+	PrivilegeLevel  int   // 权限等级，见 PrivilegeLevel* 常量
+	OpCountLimit    int64 // 表达式算力上限，为0时使用默认值 30000
+	MaxExecuteTimes int   // N# 多轮执行的最大次数，为0时不限制
+
+	ExtConflictReject bool   // 开启互斥扩展时拒绝，而不是关闭已开启的一方
+	LogExportDir      string // 日志导出目录，为空时使用系统临时目录
 
 	CommandHideFlag string // 这个是干啥的，已经忘了
 
@@ -34,6 +46,8 @@ type MsgContext struct {
 	Player *GroupPlayerInfo
 
 	AttrsManager *attrs.AttrsManager
+	BanManager   *ban.BanManager
+	HelpManager  *helpdoc.HelpManager
 	GameSystem   *GameSystemTemplateV2
 
 	TextTemplateMap      TextTemplateWithWeightDict
@@ -42,6 +56,10 @@ type MsgContext struct {
 	CommandInfo  map[string]any
 	DelegateText string
 
+	ReplyRecorder func(reply *MsgToReply) // 回复记录回调，由 Execute 设置，用于汇总本次执行产生的回复
+	afterExecute  []func()                // 消息处理结束后的回调，见 AfterExecute
+	execCtx       context.Context         // 本次指令执行的 context，见 Context
+
 	vm   *ds.Context
 	Dice DiceLike
 
@@ -50,6 +68,35 @@ type MsgContext struct {
 	CommandFormatInfo []*CommandFormatInfo
 }
 
+// AfterExecute 注册在本条消息处理结束后执行的回调，此时 CommandId 等信息已经确定
+// 适用于入站钩子中需要等待指令解析结果的场景
+func (ctx *MsgContext) AfterExecute(fn func()) {
+	ctx.afterExecute = append(ctx.afterExecute, fn)
+}
+
+// RunAfterExecute 依次执行 AfterExecute 注册的回调，由 Dice 在消息处理结束时调用
+func (ctx *MsgContext) RunAfterExecute() {
+	fns := ctx.afterExecute
+	ctx.afterExecute = nil
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// Context 返回本次指令或钩子执行的 context，执行超时后会被取消
+// 耗时较长的指令应在修改群组、玩家或属性数据前检查 Err()，被取消的指令的回复会被丢弃
+func (ctx *MsgContext) Context() context.Context {
+	if ctx.execCtx == nil {
+		return context.Background()
+	}
+	return ctx.execCtx
+}
+
+// SetContext 设置本次指令执行的 context，由骰子在执行指令和钩子前调用
+func (ctx *MsgContext) SetContext(c context.Context) {
+	ctx.execCtx = c
+}
+
 func (ctx *MsgContext) LoadRecordFetchAndClear() []*LoadRecord {
 	records := ctx.LoadRecords
 	ctx.LoadRecords = nil
@@ -29,5 +29,6 @@ type MsgToReply struct {
 	Segments    MessageSegments `jsbind:"segment" json:"segments" yaml:"-"`
 	CommandData any             // 制定一个格式，返回一些更加本质的东西，到外面去二次套壳
 
+	CommandInfo       map[string]any // 指令信息，如检定的出目与结果，见 MsgContext.CommandInfo
 	CommandFormatInfo []*CommandFormatInfo
 }
@@ -0,0 +1,12 @@
+package types
+
+// 权限等级，数值越大权限越高
+const (
+	PrivilegeLevelBanned     = -30 // 黑名单
+	PrivilegeLevelUser       = 0   // 普通用户
+	PrivilegeLevelInviter    = 40  // 邀请者
+	PrivilegeLevelGroupAdmin = 50  // 群管理员
+	PrivilegeLevelGroupOwner = 60  // 群主
+	PrivilegeLevelTrusted    = 70  // 信任用户
+	PrivilegeLevelMaster     = 100 // 骰主
+)
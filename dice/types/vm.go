@@ -49,6 +49,9 @@ func newVM(mctx *MsgContext) *ds.Context {
 	vm.Config.IgnoreDiv0 = false
 	vm.Config.DefaultDiceSideExpr = "面数 ?? 50"
 	vm.Config.OpCountLimit = 30000
+	if mctx.OpCountLimit > 0 {
+		vm.Config.OpCountLimit = ds.IntType(mctx.OpCountLimit)
+	}
 
 	getAttr := func() (*attrs.AttributesItem, error) {
 		return am.Load(groupId, userId)
@@ -0,0 +1,50 @@
+package dice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sealdice/smallseal/dice/types"
+)
+
+func TestGroupWelcome(t *testing.T) {
+	as := assert.New(t)
+
+	d := newTestDice(t, nil)
+	send := func(role string, content string) string {
+		msg := groupMessage("QQ:1", "admin", role, content)
+		msg.GroupName = "测试群"
+		return d.send(msg)
+	}
+	join := func(userID string) []*types.MsgToReply {
+		d.replies = nil
+		d.DispatchEvent("test", &types.AdapterEvent{
+			Platform: "QQ",
+			PostType: "notice",
+			Type:     types.AdapterEventGroupIncrease,
+			GroupID:  "QQ-Group:1",
+			UserID:   userID,
+		})
+		return d.replies
+	}
+
+	as.Equal("你不是管理员或master", send("", ".welcome on"))
+	as.Empty(join("QQ:2"), "welcome is off by default")
+
+	as.Equal("入群欢迎已开启", send("admin", ".welcome on"))
+	if got := join("QQ:2"); as.Len(got, 1) {
+		as.Equal("欢迎QQ:2加入本群，祝你玩得愉快", got[0].Segments.ToText())
+		as.Equal("group", got[0].MessageType)
+		as.Equal("QQ-Group:1", got[0].SendTo.GroupId)
+	}
+
+	send("owner", ".welcome set 你好 {$t新成员ID}\n请阅读群公告")
+	if got := join("QQ:3"); as.Len(got, 1) {
+		as.Equal("你好 QQ:3\n请阅读群公告", got[0].Segments.ToText())
+	}
+	as.Contains(send("admin", ".welcome show"), "入群欢迎: 开启")
+
+	send("admin", ".welcome off")
+	as.Empty(join("QQ:4"))
+}
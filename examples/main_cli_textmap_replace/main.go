@@ -78,9 +78,11 @@ func main() {
 		panic(err)
 	}
 
-	d.CallbackForSendMsg.Store("console", func(msg *types.MsgToReply) {
+	if err := d.RegisterAdapterSender("console", func(msg *types.MsgToReply) {
 		fmt.Printf("%s\n", msg.Segments.ToText())
-	})
+	}); err != nil {
+		panic(err)
+	}
 
 	line := liner.NewLiner()
 	defer line.Close()
@@ -107,7 +109,7 @@ func main() {
 		}
 		line.AppendHistory(text)
 
-		d.Execute("", &types.Message{
+		d.Execute("console", &types.Message{
 			MessageType: "group",
 			GroupID:     "cli-demo-group",
 			Sender: types.SenderBase{
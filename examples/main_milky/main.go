@@ -12,6 +12,8 @@ import (
 	"github.com/sealdice/smallseal/dice/types"
 )
 
+const milkyAdapterID = "milky"
+
 type AdapterCallbackBase2 struct {
 	dice *dice.Dice
 }
@@ -30,7 +32,7 @@ func (cb *AdapterCallbackBase2) OnMessageReceived(info *adapters.MessageSendCall
 	fmt.Printf("OnMessageReceived: %v, msg=%s\n", string(jsonInfo), info.Message.Segments.ToText())
 	// 将接收到的消息转发给dice对象处理
 	if cb.dice != nil && info.Message != nil {
-		cb.dice.Execute("", info.Message)
+		cb.dice.Execute(milkyAdapterID, info.Message)
 	}
 }
 func (cb *AdapterCallbackBase2) OnEvent(evt *types.AdapterEvent) {
@@ -38,7 +40,7 @@ func (cb *AdapterCallbackBase2) OnEvent(evt *types.AdapterEvent) {
 		return
 	}
 	if cb.dice != nil {
-		cb.dice.DispatchEvent("", evt)
+		cb.dice.DispatchEvent(milkyAdapterID, evt)
 	}
 }
 
@@ -65,30 +67,18 @@ func main() {
 	}
 	conn.SetCallback(callback)
 
-	d.CallbackForSendMsg.Store("milky", func(msg *types.MsgToReply) {
-		if conn != nil {
-			fmt.Println("callback send msg, msg=", msg.Segments.ToText())
-			jsonInfo, err := json.Marshal(msg)
-			if err != nil {
-				fmt.Printf("callback send msg, marshal err, msg=%v, err=%v\n", msg, err)
-				return
-			}
-			fmt.Printf("callback send msg, json=%s\n", string(jsonInfo))
-
-			if msg.MessageType == "private" {
-				conn.MsgSendToPerson(&adapters.MessageSendRequest{
-					TargetId: msg.SendTo.UserId,
-					Segments: msg.Segments,
-				})
-			}
-
-			if msg.MessageType == "group" {
-				conn.MsgSendToGroup(&adapters.MessageSendRequest{
-					TargetId: msg.SendTo.GroupId,
-					Segments: msg.Segments,
-				})
-			}
+	if err := d.RegisterAdapter(milkyAdapterID, conn); err != nil {
+		panic(err)
+	}
+	_, _ = d.RegisterMessageOutHook("examples/milky-debug", types.HookPriorityLow, func(_ types.DiceLike, _ string, msg *types.MsgToReply) types.HookResult {
+		fmt.Println("callback send msg, msg=", msg.Segments.ToText())
+		jsonInfo, err := json.Marshal(msg)
+		if err != nil {
+			fmt.Printf("callback send msg, marshal err, msg=%v, err=%v\n", msg, err)
+			return types.HookResultContinue
 		}
+		fmt.Printf("callback send msg, json=%s\n", string(jsonInfo))
+		return types.HookResultContinue
 	})
 
 	fmt.Println("等待消息中...")
@@ -18,6 +18,8 @@ import (
 	"go.uber.org/zap"
 )
 
+const ob11AdapterID = "ob11"
+
 type ob11Callback struct {
 	dice *dice.Dice
 }
@@ -36,7 +38,7 @@ func (cb *ob11Callback) OnMessageReceived(info *adapters.MessageSendCallbackInfo
 	fmt.Printf("OnMessageReceived: %s, msg=%s\n", string(jsonInfo), info.Message.Segments.ToText())
 
 	if cb.dice != nil && info.Message != nil {
-		cb.dice.Execute("", info.Message)
+		cb.dice.Execute(ob11AdapterID, info.Message)
 	}
 }
 
@@ -54,7 +56,7 @@ func (cb *ob11Callback) OnEvent(evt *types.AdapterEvent) {
 	}
 	fmt.Printf("OnEvent: %s\n", string(jsonEvt))
 	if cb.dice != nil {
-		cb.dice.DispatchEvent("", evt)
+		cb.dice.DispatchEvent(ob11AdapterID, evt)
 	}
 }
 
@@ -88,27 +90,11 @@ func main() {
 	callback := &ob11Callback{dice: d}
 	conn.SetCallback(callback)
 
-	go conn.Serve(ctx)
+	if err := d.RegisterAdapter(ob11AdapterID, conn); err != nil {
+		logger.Fatal("failed to register adapter", zap.Error(err))
+	}
 
-	d.CallbackForSendMsg.Store("ob11", func(msg *types.MsgToReply) {
-		if msg == nil {
-			return
-		}
-
-		if msg.MessageType == "private" {
-			_, _ = conn.MsgSendToPerson(&adapters.MessageSendRequest{
-				TargetId: msg.SendTo.UserId,
-				Segments: msg.Segments,
-			})
-		}
-
-		if msg.MessageType == "group" {
-			_, _ = conn.MsgSendToGroup(&adapters.MessageSendRequest{
-				TargetId: msg.SendTo.GroupId,
-				Segments: msg.Segments,
-			})
-		}
-	})
+	go conn.Serve(ctx)
 
 	fmt.Println("等待消息中... 使用 Ctrl+C 退出")
 
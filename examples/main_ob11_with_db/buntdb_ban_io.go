@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/sealdice/smallseal/dice/ban"
+	"github.com/tidwall/buntdb"
+)
+
+type buntBanIO struct {
+	db *buntdb.DB
+}
+
+func newBuntBanIO(db *buntdb.DB) *buntBanIO {
+	return &buntBanIO{db: db}
+}
+
+func banKey(id string) string {
+	return "ban:" + encodeKeyPart(id)
+}
+
+func (io *buntBanIO) List() ([]*ban.BanListItem, error) {
+	var items []*ban.BanListItem
+	err := io.db.View(func(tx *buntdb.Tx) error {
+		var iterErr error
+		err := tx.AscendKeys("ban:*", func(_, value string) bool {
+			item := &ban.BanListItem{}
+			if err := json.Unmarshal([]byte(value), item); err != nil {
+				iterErr = err
+				return false
+			}
+			items = append(items, item)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		return iterErr
+	})
+	return items, err
+}
+
+func (io *buntBanIO) Puts(items []*ban.BanListItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return io.db.Update(func(tx *buntdb.Tx) error {
+		for _, item := range items {
+			if item == nil {
+				continue
+			}
+			if item.ID == "" {
+				return errors.New("id cannot be empty")
+			}
+			payload, err := json.Marshal(item)
+			if err != nil {
+				return err
+			}
+			if _, _, err := tx.Set(banKey(item.ID), string(payload), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (io *buntBanIO) DeleteById(id string) error {
+	return io.db.Update(func(tx *buntdb.Tx) error {
+		if _, err := tx.Delete(banKey(id)); err != nil && !errors.Is(err, buntdb.ErrNotFound) {
+			return err
+		}
+		return nil
+	})
+}
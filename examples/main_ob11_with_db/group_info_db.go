@@ -42,6 +42,7 @@ type storedGroupInfo struct {
 	GroupWelcomeMessage string
 	EnteredTime         int64
 	InviteUserID        string
+	AdapterId           string
 	DefaultHelpGroup    string
 	RecentDiceSendTime  int64
 	UpdatedAtTime       int64
@@ -72,6 +73,7 @@ func groupInfoToStored(info *types.GroupInfo) *storedGroupInfo {
 		GroupWelcomeMessage: info.GroupWelcomeMessage,
 		EnteredTime:         info.EnteredTime,
 		InviteUserID:        info.InviteUserID,
+		AdapterId:           info.AdapterId,
 		DefaultHelpGroup:    info.DefaultHelpGroup,
 		RecentDiceSendTime:  info.RecentDiceSendTime,
 		UpdatedAtTime:       time.Now().Unix(),
@@ -105,6 +107,7 @@ func storedToGroupInfo(stored *storedGroupInfo) *types.GroupInfo {
 		GroupWelcomeMessage: stored.GroupWelcomeMessage,
 		EnteredTime:         stored.EnteredTime,
 		InviteUserID:        stored.InviteUserID,
+		AdapterId:           stored.AdapterId,
 		DefaultHelpGroup:    stored.DefaultHelpGroup,
 		RecentDiceSendTime:  stored.RecentDiceSendTime,
 		UpdatedAtTime:       stored.UpdatedAtTime,
@@ -204,24 +207,65 @@ func (m *buntGroupInfoManager) Store(groupId string, info *types.GroupInfo) {
 	if groupId == "" || info == nil {
 		return
 	}
+	if err := m.write(groupId, info); err != nil {
+		fmt.Printf("GroupInfo store error: %v\n", err)
+		return
+	}
+	m.cache.Store(groupId, info)
+}
+
+// Flush 将缓存中的群组信息全部写回数据库，供 Dice.Close 调用
+func (m *buntGroupInfoManager) Flush() error {
+	var errs []error
+	m.cache.Range(func(groupId string, info *types.GroupInfo) bool {
+		if err := m.write(groupId, info); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", groupId, err))
+		}
+		return true
+	})
+	return errors.Join(errs...)
+}
+
+// Range 遍历数据库中的全部群组信息，供自动退群使用
+func (m *buntGroupInfoManager) Range(fn func(groupId string, info *types.GroupInfo) bool) {
+	var ids []string
+	err := m.db.View(func(tx *buntdb.Tx) error {
+		return tx.AscendKeys("group:*", func(_, value string) bool {
+			var stored storedGroupInfo
+			if err := json.Unmarshal([]byte(value), &stored); err == nil && stored.GroupId != "" {
+				ids = append(ids, stored.GroupId)
+			}
+			return true
+		})
+	})
+	if err != nil {
+		fmt.Printf("GroupInfo range error: %v\n", err)
+		return
+	}
+	for _, id := range ids {
+		info, ok := m.Load(id)
+		if !ok {
+			continue
+		}
+		if !fn(id, info) {
+			return
+		}
+	}
+}
+
+func (m *buntGroupInfoManager) write(groupId string, info *types.GroupInfo) error {
 	stored := groupInfoToStored(info)
 	if stored == nil {
-		return
+		return nil
 	}
 	payload, err := json.Marshal(stored)
 	if err != nil {
-		fmt.Printf("GroupInfo marshal error: %v\n", err)
-		return
+		return err
 	}
-	err = m.db.Update(func(tx *buntdb.Tx) error {
+	return m.db.Update(func(tx *buntdb.Tx) error {
 		_, _, e := tx.Set(groupKey(groupId), string(payload), nil)
 		return e
 	})
-	if err != nil {
-		fmt.Printf("GroupInfo store error: %v\n", err)
-		return
-	}
-	m.cache.Store(groupId, info)
 }
 
 func (m *buntGroupInfoManager) Delete(groupId string) {
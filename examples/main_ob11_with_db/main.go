@@ -10,6 +10,7 @@ import (
 	"os/signal"
 	"runtime/debug"
 	"syscall"
+	"time"
 
 	"github.com/sealdice/smallseal/adapters"
 	"github.com/sealdice/smallseal/dice"
@@ -19,6 +20,8 @@ import (
 	"go.uber.org/zap"
 )
 
+const ob11AdapterID = "ob11"
+
 type ob11Callback struct {
 	dice *dice.Dice
 }
@@ -42,7 +45,18 @@ func (cb *ob11Callback) OnMessageReceived(info *adapters.MessageSendCallbackInfo
 	}
 
 	if cb.dice != nil && info.Message != nil {
-		cb.dice.Execute("", info.Message)
+		if err := cb.dice.TrySubmit(ob11AdapterID, info.Message); err != nil {
+			fmt.Printf("[Msg] dropped: %v, stats=%+v\n", err, cb.dice.PipelineStats())
+		}
+	}
+}
+
+func logExecuteResult(_ string, _ *types.Message, result *dice.ExecuteResult) {
+	if result.IsCommand() {
+		fmt.Printf("[Cmd] #%d %s/%s solved=%v replies=%d cost=%s\n", result.CommandId, result.ExtName, result.Command, result.Solved, len(result.Replies), result.Duration)
+	}
+	if result.Err != nil {
+		fmt.Printf("[Cmd] #%d error: %v\n", result.CommandId, result.Err)
 	}
 }
 
@@ -60,7 +74,7 @@ func (cb *ob11Callback) OnEvent(evt *types.AdapterEvent) {
 	}
 	fmt.Printf("OnEvent: %s\n", string(jsonEvt))
 	if cb.dice != nil {
-		cb.dice.DispatchEvent("", evt)
+		cb.dice.DispatchEvent(ob11AdapterID, evt)
 	}
 }
 
@@ -97,39 +111,42 @@ func main() {
 	d := dice.NewDice()
 	d.AttrsSetIO(newBuntAttrsIO(db))
 	d.GroupInfoManager = newBuntGroupInfoManager(db)
+	if err := d.BanSetIO(newBuntBanIO(db)); err != nil {
+		logger.Fatal("failed to load ban list", zap.Error(err))
+	}
+	if path := os.Getenv("SMALLSEAL_CONFIG"); path != "" {
+		if err := d.WatchConfigFile(path, 0); err != nil {
+			logger.Fatal("failed to load config", zap.String("path", path), zap.Error(err))
+		}
+	}
 
-	// 确保程序退出时保存所有未保存的属性数据
-	defer d.SaveAll()
+	if err := d.StartPipeline(dice.PipelineConfig{OnResult: logExecuteResult}); err != nil {
+		logger.Fatal("failed to start pipeline", zap.Error(err))
+	}
+	if err := d.StartGroupSweeper(); err != nil {
+		logger.Fatal("failed to start group sweeper", zap.Error(err))
+	}
 
 	conn := newOB11ConnItem()
 
 	callback := &ob11Callback{dice: d}
 	conn.SetCallback(callback)
 
-	go conn.Serve(ctx)
-
-	d.CallbackForSendMsg.Store("ob11", func(msg *types.MsgToReply) {
-		if msg == nil {
-			return
-		}
-
-		if msg.MessageType == "private" {
-			_, _ = conn.MsgSendToPerson(&adapters.MessageSendRequest{
-				TargetId: msg.SendTo.UserId,
-				Segments: msg.Segments,
-			})
-		}
+	if err := d.RegisterAdapter(ob11AdapterID, conn); err != nil {
+		logger.Fatal("failed to register adapter", zap.Error(err))
+	}
 
-		if msg.MessageType == "group" {
-			_, _ = conn.MsgSendToGroup(&adapters.MessageSendRequest{
-				TargetId: msg.SendTo.GroupId,
-				Segments: msg.Segments,
-			})
-		}
-	})
+	go conn.Serve(ctx)
 
 	fmt.Println("等待消息中... 使用 Ctrl+C 退出")
 
 	<-ctx.Done()
 	conn.Close()
+
+	// 排空队列并保存所有未保存的数据
+	closeCtx, closeCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer closeCancel()
+	if err := d.Close(closeCtx); err != nil {
+		logger.Warn("dice close incomplete", zap.Error(err))
+	}
 }
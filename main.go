@@ -35,9 +35,11 @@ func main() {
 	fmt.Println("Small Seal Shell v0.0.1")
 	ccTimes := 0
 
-	d.CallbackForSendMsg.Store("console", func(msg *types.MsgToReply) {
+	if err := d.RegisterAdapterSender("console", func(msg *types.MsgToReply) {
 		fmt.Printf("%s\n", msg.Segments.ToText())
-	})
+	}); err != nil {
+		panic(err)
+	}
 
 	for {
 		if text, err := line.Prompt(">>> "); err == nil {
@@ -46,7 +48,7 @@ func main() {
 			}
 			line.AppendHistory(text)
 
-			d.Execute("", &types.Message{
+			d.Execute("console", &types.Message{
 				MessageType: "group",
 				GroupID:     "1000",
 